@@ -0,0 +1,238 @@
+// Command kafka-dlq lists and replays the messages of KAFKA_DLQ_TOPIC. The
+// replayed messages are marked in the DLQ topic and skipped by later runs,
+// -replayed selects them again.
+//
+//	kafka-dlq [-config-path .] [-config-name .env] list [filters]
+//	kafka-dlq [-config-path .] [-config-name .env] replay [filters] [-messages 0:12,1:40] [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+)
+
+func main() {
+	configPath := flag.String("config-path", ".", "directory of the env config")
+	configName := flag.String("config-name", ".env", "name of the env config")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := common_utils.LoadBaseConfig(*configPath, *configName)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := kafka.NewKafkaClientWithAuth(cfg)
+	if err != nil {
+		fail(err)
+	}
+
+	switch flag.Arg(0) {
+	case "list":
+		err = list(ctx, client, flag.Args()[1:])
+	case "replay":
+		err = replay(ctx, client, flag.Args()[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: kafka-dlq [-config-path dir] [-config-name name] <list|replay> [flags]\n\n")
+	flag.PrintDefaults()
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "kafka-dlq: %v\n", err)
+	os.Exit(1)
+}
+
+type filterFlags struct {
+	topic    string
+	since    time.Duration
+	from     string
+	to       string
+	errorMsg string
+	limit    int
+	replayed bool
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.topic, "topic", "", "original topic of the message")
+	fs.DurationVar(&f.since, "since", 0, "only messages newer than the duration, e.g. 24h")
+	fs.StringVar(&f.from, "from", "", "only messages after the time (RFC3339)")
+	fs.StringVar(&f.to, "to", "", "only messages before the time (RFC3339)")
+	fs.StringVar(&f.errorMsg, "error", "", "only messages whose error contains the text")
+	fs.IntVar(&f.limit, "limit", 0, "maximum number of messages")
+	fs.BoolVar(&f.replayed, "replayed", false, "include the messages replayed before")
+}
+
+func (f *filterFlags) filter() (kafka.DLQFilter, error) {
+	filter := kafka.DLQFilter{
+		Topic:           f.topic,
+		ErrorContains:   f.errorMsg,
+		Limit:           f.limit,
+		IncludeReplayed: f.replayed,
+	}
+
+	if f.since > 0 {
+		filter.From = time.Now().Add(-f.since)
+	}
+	if f.from != "" {
+		from, err := time.Parse(time.RFC3339, f.from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid -from: %w", err)
+		}
+		filter.From = from
+	}
+	if f.to != "" {
+		to, err := time.Parse(time.RFC3339, f.to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid -to: %w", err)
+		}
+		filter.To = to
+	}
+
+	return filter, nil
+}
+
+func list(ctx context.Context, client kafka.IClient, args []string) error {
+	var flags filterFlags
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	flags.register(fs)
+	fs.Parse(args)
+
+	filter, err := flags.filter()
+	if err != nil {
+		return err
+	}
+
+	messages, err := client.ListDLQ(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	printMessages(messages)
+	return nil
+}
+
+func replay(ctx context.Context, client kafka.IClient, args []string) error {
+	var flags filterFlags
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.register(fs)
+	selected := fs.String("messages", "", "comma separated dlq partition:offset pairs to replay, default all matching messages")
+	dryRun := fs.Bool("dry-run", false, "only print the messages which would be replayed")
+	fs.Parse(args)
+
+	filter, err := flags.filter()
+	if err != nil {
+		return err
+	}
+
+	positions, err := parsePositions(*selected)
+	if err != nil {
+		return err
+	}
+
+	messages, err := client.ListDLQ(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if len(positions) > 0 {
+		filtered := make([]kafka.DLQMessage, 0, len(positions))
+		for _, msg := range messages {
+			if positions[position{msg.Partition, msg.Offset}] {
+				filtered = append(filtered, msg)
+			}
+		}
+		messages = filtered
+	}
+
+	printMessages(messages)
+	if *dryRun || len(messages) == 0 {
+		return nil
+	}
+
+	if err := client.NewPublisher(); err != nil {
+		return err
+	}
+
+	replayed, err := client.ReplayDLQ(ctx, messages...)
+	fmt.Printf("\nreplayed %d of %d messages\n", replayed, len(messages))
+	return err
+}
+
+type position struct {
+	partition int
+	offset    int64
+}
+
+func parsePositions(value string) (map[position]bool, error) {
+	positions := make(map[position]bool)
+	if value == "" {
+		return positions, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		partition, offset, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid message position %q, expected partition:offset", pair)
+		}
+
+		p, err := strconv.Atoi(partition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition in %q: %w", pair, err)
+		}
+		o, err := strconv.ParseInt(offset, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in %q: %w", pair, err)
+		}
+		positions[position{p, o}] = true
+	}
+
+	return positions, nil
+}
+
+func printMessages(messages []kafka.DLQMessage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DLQ\tTIME\tTOPIC\tORIGIN\tFAILED BY\tREPLAYS\tREPLAYED\tKEY\tERROR")
+	for _, msg := range messages {
+		fmt.Fprintf(w, "%d:%d\t%s\t%s\t%s\t%s\t%d\t%t\t%s\t%s\n",
+			msg.Partition,
+			msg.Offset,
+			msg.Timestamp.Format(time.RFC3339),
+			msg.OriginalTopic,
+			msg.Origin,
+			msg.FailedBy,
+			msg.ReplayCount,
+			msg.Replayed,
+			msg.Key,
+			msg.Error,
+		)
+	}
+	w.Flush()
+}
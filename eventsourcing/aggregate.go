@@ -0,0 +1,154 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dispenal/go-common/kafka"
+)
+
+// AggregateType is the type of an aggregate, e.g. "order".
+type AggregateType string
+
+var (
+	ErrAggregateNotFound   = errors.New("aggregate not found")
+	ErrInvalidEventVersion = errors.New("invalid event version")
+	ErrInvalidAggregate    = errors.New("event does not belong to the aggregate")
+)
+
+// Event is a kafka.Event applied to an aggregate. AggregateVersion is the
+// position of the event in the stream of the aggregate, it is unrelated to
+// the schema Version used by the upcasters.
+type Event struct {
+	kafka.Event
+	AggregateID      string
+	AggregateType    AggregateType
+	AggregateVersion uint64
+	// Position is the global position of the event in the store, 0 until the
+	// event is saved.
+	Position int64
+}
+
+// WhenFunc changes the state of the aggregate according to the event, it is
+// called for new events and for the events loaded from the store.
+type WhenFunc func(event Event) error
+
+// Aggregate is implemented by every aggregate embedding *AggregateBase.
+type Aggregate interface {
+	GetID() string
+	GetType() AggregateType
+	GetVersion() uint64
+	GetUncommittedEvents() []Event
+	ClearUncommittedEvents()
+	RaiseEvent(event Event) error
+}
+
+// AggregateBase tracks the version and the uncommitted events of an
+// aggregate, the state changes are left to the when handler:
+//
+//	type Order struct {
+//		*eventsourcing.AggregateBase
+//		Status string
+//	}
+//
+//	func NewOrder(id string) *Order {
+//		order := &Order{}
+//		order.AggregateBase = eventsourcing.NewAggregateBase(id, "order", order.When)
+//		return order
+//	}
+//
+//	func (o *Order) When(event eventsourcing.Event) error {
+//		switch event.EventType {
+//		case OrderCreated:
+//			o.Status = "created"
+//		}
+//		return nil
+//	}
+//
+// The exported fields are stored in the snapshots together with the state of
+// the aggregate.
+type AggregateBase struct {
+	ID      string
+	Type    AggregateType
+	Version uint64
+
+	uncommittedEvents []Event
+	when              WhenFunc
+}
+
+func NewAggregateBase(id string, aggregateType AggregateType, when WhenFunc) *AggregateBase {
+	return &AggregateBase{
+		ID:   id,
+		Type: aggregateType,
+		when: when,
+	}
+}
+
+func (a *AggregateBase) GetID() string {
+	return a.ID
+}
+
+func (a *AggregateBase) GetType() AggregateType {
+	return a.Type
+}
+
+func (a *AggregateBase) GetVersion() uint64 {
+	return a.Version
+}
+
+// GetUncommittedEvents returns the events applied since the aggregate was
+// loaded or saved.
+func (a *AggregateBase) GetUncommittedEvents() []Event {
+	return a.uncommittedEvents
+}
+
+func (a *AggregateBase) ClearUncommittedEvents() {
+	a.uncommittedEvents = nil
+}
+
+// Apply applies a new event to the aggregate and records it as uncommitted,
+// the event gets the next version of the aggregate.
+func (a *AggregateBase) Apply(event *kafka.Event) error {
+	applied := Event{
+		Event:            *event,
+		AggregateID:      a.ID,
+		AggregateType:    a.Type,
+		AggregateVersion: a.Version + 1,
+	}
+
+	if err := a.when(applied); err != nil {
+		return err
+	}
+
+	a.Version = applied.AggregateVersion
+	a.uncommittedEvents = append(a.uncommittedEvents, applied)
+	return nil
+}
+
+// RaiseEvent applies an event loaded from the store, the event must be the
+// next one of the aggregate.
+func (a *AggregateBase) RaiseEvent(event Event) error {
+	if event.AggregateID != a.ID {
+		return fmt.Errorf("%w: %s", ErrInvalidAggregate, event.AggregateID)
+	}
+	if event.AggregateVersion != a.Version+1 {
+		return fmt.Errorf("%w: expected %d, got %d", ErrInvalidEventVersion, a.Version+1, event.AggregateVersion)
+	}
+
+	if err := a.when(event); err != nil {
+		return err
+	}
+
+	a.Version = event.AggregateVersion
+	return nil
+}
+
+// Load applies the events loaded from the store one after the other.
+func (a *AggregateBase) Load(events []Event) error {
+	for _, event := range events {
+		if err := a.RaiseEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
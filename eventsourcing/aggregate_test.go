@@ -0,0 +1,94 @@
+package eventsourcing
+
+import (
+	"testing"
+
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	counterIncremented kafka.EventType = "CounterIncremented"
+	counterType        AggregateType   = "counter"
+)
+
+type counter struct {
+	*AggregateBase
+	Count int
+}
+
+func newCounter(id string) *counter {
+	c := &counter{}
+	c.AggregateBase = NewAggregateBase(id, counterType, c.When)
+	return c
+}
+
+func (c *counter) When(event Event) error {
+	switch event.EventType {
+	case counterIncremented:
+		c.Count++
+	}
+	return nil
+}
+
+func TestAggregateApply(t *testing.T) {
+	c := newCounter("1")
+
+	assert.NoError(t, c.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+	assert.NoError(t, c.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+
+	assert.Equal(t, 2, c.Count)
+	assert.Equal(t, uint64(2), c.GetVersion())
+
+	events := c.GetUncommittedEvents()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "1", events[1].AggregateID)
+	assert.Equal(t, counterType, events[1].AggregateType)
+	assert.Equal(t, uint64(2), events[1].AggregateVersion)
+
+	c.ClearUncommittedEvents()
+	assert.Empty(t, c.GetUncommittedEvents())
+}
+
+func TestAggregateLoad(t *testing.T) {
+	c := newCounter("1")
+
+	err := c.Load([]Event{
+		{AggregateID: "1", AggregateVersion: 1, Event: kafka.Event{EventType: counterIncremented}},
+		{AggregateID: "1", AggregateVersion: 2, Event: kafka.Event{EventType: counterIncremented}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.Count)
+	assert.Empty(t, c.GetUncommittedEvents())
+
+	err = c.RaiseEvent(Event{AggregateID: "1", AggregateVersion: 4})
+	assert.ErrorIs(t, err, ErrInvalidEventVersion)
+
+	err = c.RaiseEvent(Event{AggregateID: "2", AggregateVersion: 3})
+	assert.ErrorIs(t, err, ErrInvalidAggregate)
+}
+
+func TestAggregateSnapshot(t *testing.T) {
+	c := newCounter("1")
+	assert.NoError(t, c.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+
+	state, err := common_utils.Marshal(c)
+	assert.NoError(t, err)
+
+	restored := newCounter("1")
+	assert.NoError(t, common_utils.Unmarshal(state, restored))
+	assert.Equal(t, 1, restored.Count)
+	assert.Equal(t, uint64(1), restored.GetVersion())
+
+	assert.NoError(t, restored.RaiseEvent(Event{AggregateID: "1", AggregateVersion: 2, Event: kafka.Event{EventType: counterIncremented}}))
+	assert.Equal(t, 2, restored.Count)
+}
+
+func TestShouldSnapshot(t *testing.T) {
+	assert.False(t, shouldSnapshot(0, 0, 200))
+	assert.False(t, shouldSnapshot(100, 0, 99))
+	assert.True(t, shouldSnapshot(100, 98, 102))
+	assert.True(t, shouldSnapshot(100, 99, 100))
+	assert.False(t, shouldSnapshot(100, 100, 199))
+}
@@ -0,0 +1,144 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// Headers of the projected messages, the payload is the kafka.Event of the
+// stored event.
+const (
+	AggregateIDHeader      = "aggregate_id"
+	AggregateTypeHeader    = "aggregate_type"
+	AggregateVersionHeader = "aggregate_version"
+)
+
+// TopicFunc returns the kafka topic of a stored event.
+type TopicFunc func(event Event) string
+
+// Projection feeds the stored events in position order to kafka and records
+// the position of the last published event under its name, so a restarted
+// projection continues where it stopped. The events are keyed by aggregate ID,
+// keeping the events of an aggregate in order on one partition. Delivery is
+// at least once.
+type Projection struct {
+	name         string
+	store        *PostgresEventStore
+	client       kafka.IClient
+	topic        TopicFunc
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func NewProjection(config *common_utils.BaseConfig, name string, store *PostgresEventStore, client kafka.IClient, topic TopicFunc) *Projection {
+	projection := &Projection{
+		name:         name,
+		store:        store,
+		client:       client,
+		topic:        topic,
+		pollInterval: config.ProjectionPollInterval,
+		batchSize:    config.ProjectionBatchSize,
+	}
+
+	if projection.pollInterval <= 0 {
+		projection.pollInterval = common_utils.DefaultPollInterval
+	}
+	if projection.batchSize <= 0 {
+		projection.batchSize = common_utils.DefaultPollBatchSize
+	}
+
+	return projection
+}
+
+// Run projects the events until ctx is cancelled, see common_utils.RunPoll.
+func (p *Projection) Run(ctx context.Context) error {
+	return common_utils.RunPoll(ctx, "projection "+p.name, p.pollInterval, p.batchSize, p.RunBatch)
+}
+
+// RunBatch publishes the next batch of events and returns the number of
+// published events. The projection row is locked for the batch, so only one
+// instance of a projection publishes at a time. The batch stops at the first
+// failed event, the position of the last published one is committed before
+// the error is returned.
+func (p *Projection) RunBatch(ctx context.Context) (int, error) {
+	var projected int
+	var publishErr error
+
+	err := common_utils.ExecTx(ctx, p.store.pgxPool, func(tx pgx.Tx) error {
+		var err error
+		projected, publishErr, err = p.projectTx(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return projected, publishErr
+}
+
+// projectTx publishes the events after the position of the projection and
+// records the new position in tx. The returned error aborts tx, the publish
+// error does not.
+func (p *Projection) projectTx(ctx context.Context, tx pgx.Tx) (projected int, publishErr error, err error) {
+	position, err := p.lockPosition(ctx, tx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	events, err := p.store.loadEventsAfter(ctx, tx, position, p.batchSize)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, event := range events {
+		publishErr = p.client.PublishWithOptions(ctx, p.topic(event), event.Event, kafka.PublishOptions{
+			Key:      []byte(event.AggregateID),
+			Balancer: kafka.BalancerHash,
+			Headers: map[string]string{
+				AggregateIDHeader:      event.AggregateID,
+				AggregateTypeHeader:    string(event.AggregateType),
+				AggregateVersionHeader: strconv.FormatUint(event.AggregateVersion, 10),
+			},
+		})
+		if publishErr != nil {
+			break
+		}
+		position = event.Position
+		projected++
+	}
+
+	if projected > 0 {
+		_, err = tx.Exec(ctx,
+			fmt.Sprintf("UPDATE %s_projections SET position = $2, updated_at = now() WHERE name = $1", p.store.table),
+			p.name, position,
+		)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return projected, publishErr, nil
+}
+
+func (p *Projection) lockPosition(ctx context.Context, tx pgx.Tx) (int64, error) {
+	_, err := tx.Exec(ctx,
+		fmt.Sprintf("INSERT INTO %s_projections (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", p.store.table),
+		p.name,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var position int64
+	err = tx.QueryRow(ctx,
+		fmt.Sprintf("SELECT position FROM %s_projections WHERE name = $1 FOR UPDATE", p.store.table),
+		p.name,
+	).Scan(&position)
+	return position, err
+}
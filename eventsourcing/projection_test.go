@@ -0,0 +1,88 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dispenal/go-common/kafka"
+	mock_kafka "github.com/dispenal/go-common/kafka/mock"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestProjectionPositions(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	store := newTestStore(t, &common_utils.BaseConfig{})
+
+	c := newCounter("counter-1")
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, c.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+	}
+	assert.NoError(t, store.Save(ctx, c))
+
+	events, err := store.LoadEvents(ctx, "counter-1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 4)
+
+	publishErr := errors.New("broker unavailable")
+	failing := events[2].EventID
+
+	var published []kafka.PublishOptions
+	client := mock_kafka.NewMockIClient(ctrl)
+	client.EXPECT().PublishWithOptions(gomock.Any(), "counters", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, topic string, event kafka.Event, opts kafka.PublishOptions) error {
+			if event.EventID == failing {
+				return publishErr
+			}
+			published = append(published, opts)
+			return nil
+		}).AnyTimes()
+
+	projection := NewProjection(&common_utils.BaseConfig{}, "counters", store, client, func(event Event) string {
+		return "counters"
+	})
+	position := func() int64 {
+		var position int64
+		err := store.pgxPool.QueryRow(ctx,
+			fmt.Sprintf("SELECT position FROM %s_projections WHERE name = $1", store.table), "counters",
+		).Scan(&position)
+		assert.NoError(t, err)
+		return position
+	}
+
+	t.Run("Commit the position of the published events before a failed one", func(t *testing.T) {
+		projected, err := projection.RunBatch(ctx)
+		assert.ErrorIs(t, err, publishErr)
+		assert.Equal(t, 2, projected)
+		assert.Equal(t, events[1].Position, position())
+	})
+
+	t.Run("Continue after the committed position", func(t *testing.T) {
+		failing = ""
+
+		projected, err := projection.RunBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, projected)
+		assert.Equal(t, events[3].Position, position())
+		assert.Len(t, published, 4)
+
+		projected, err = projection.RunBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, projected)
+	})
+
+	t.Run("Publish the aggregate as headers", func(t *testing.T) {
+		if !assert.Len(t, published, 4) {
+			return
+		}
+		opts := published[3]
+		assert.Equal(t, []byte("counter-1"), opts.Key)
+		assert.Equal(t, "counter-1", opts.Headers[AggregateIDHeader])
+		assert.Equal(t, string(counterType), opts.Headers[AggregateTypeHeader])
+		assert.Equal(t, "4", opts.Headers[AggregateVersionHeader])
+	})
+}
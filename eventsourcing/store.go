@@ -0,0 +1,304 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultTable = "events"
+
+	uniqueViolation = "23505"
+
+	// maxIdentifierLength is the length postgres truncates identifiers to.
+	maxIdentifierLength = 63
+)
+
+// ErrConcurrencyConflict is returned by Save when another writer appended
+// events to the aggregate since it was loaded, reload it and retry.
+var ErrConcurrencyConflict = errors.New("aggregate was modified concurrently")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	position          BIGSERIAL PRIMARY KEY,
+	aggregate_id      TEXT NOT NULL,
+	aggregate_type    TEXT NOT NULL,
+	aggregate_version BIGINT NOT NULL,
+	event_id          TEXT NOT NULL UNIQUE,
+	event_type        TEXT NOT NULL,
+	version           BIGINT NOT NULL,
+	data              BYTEA,
+	metadata          BYTEA,
+	timestamp         TIMESTAMPTZ NOT NULL,
+	CONSTRAINT %[3]s UNIQUE (aggregate_id, aggregate_version)
+);
+CREATE TABLE IF NOT EXISTS %[1]s_snapshots (
+	aggregate_id      TEXT PRIMARY KEY,
+	aggregate_type    TEXT NOT NULL,
+	aggregate_version BIGINT NOT NULL,
+	state             BYTEA NOT NULL,
+	created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS %[1]s_projections (
+	name       TEXT PRIMARY KEY,
+	position   BIGINT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS %[2]s_aggregate_idx ON %[1]s (aggregate_id, aggregate_version);
+`
+
+const eventColumns = "position, aggregate_id, aggregate_type, aggregate_version, event_id, event_type, version, data, metadata, timestamp"
+
+// EventStore appends the events of aggregates and loads aggregates back from
+// their events.
+type EventStore interface {
+	Migrate(ctx context.Context) error
+	Load(ctx context.Context, aggregate Aggregate) error
+	Save(ctx context.Context, aggregate Aggregate) error
+	SaveTx(ctx context.Context, tx pgx.Tx, aggregate Aggregate) error
+	Exists(ctx context.Context, aggregateID string) (bool, error)
+	LoadEvents(ctx context.Context, aggregateID string, fromVersion uint64) ([]Event, error)
+}
+
+// PostgresEventStore stores the events in the EVENT_STORE_TABLE table, the
+// snapshots and the projection positions in the tables of the same name with
+// the _snapshots and _projections suffix. Concurrent writes of the same
+// aggregate are rejected by the unique aggregate version.
+type PostgresEventStore struct {
+	table             string
+	versionConstraint string
+	pgxPool           *pgxpool.Pool
+	snapshotEvery     uint64
+}
+
+// NewEventStore creates the postgres event store, a snapshot of the aggregate
+// is saved every EVENT_STORE_SNAPSHOT_FREQUENCY events (never when 0).
+func NewEventStore(config *common_utils.BaseConfig, pgxPool *pgxpool.Pool) *PostgresEventStore {
+	table := config.EventStoreTable
+	if table == "" {
+		table = defaultTable
+	}
+	if !common_utils.ValidTableName(table) {
+		common_utils.PanicAppError(fmt.Sprintf("invalid event store table name: %s", table), 500)
+	}
+
+	return &PostgresEventStore{
+		table:             table,
+		versionConstraint: versionConstraint(table),
+		pgxPool:           pgxPool,
+		snapshotEvery:     uint64(config.EventStoreSnapshotFreq),
+	}
+}
+
+// versionConstraint returns the name of the unique aggregate version
+// constraint of table.
+func versionConstraint(table string) string {
+	name := common_utils.IndexName(table) + "_aggregate_version_key"
+	if len(name) > maxIdentifierLength {
+		name = name[:maxIdentifierLength]
+	}
+	return name
+}
+
+// Migrate creates the event, snapshot and projection tables when they do not
+// exist.
+func (s *PostgresEventStore) Migrate(ctx context.Context) error {
+	index := common_utils.IndexName(s.table)
+	_, err := s.pgxPool.Exec(ctx, fmt.Sprintf(schema, s.table, index, s.versionConstraint))
+	return err
+}
+
+// Load restores the aggregate from its latest snapshot and the events stored
+// after it. ErrAggregateNotFound is returned when nothing is stored.
+func (s *PostgresEventStore) Load(ctx context.Context, aggregate Aggregate) error {
+	found, err := s.loadSnapshot(ctx, aggregate)
+	if err != nil {
+		return err
+	}
+
+	events, err := s.LoadEvents(ctx, aggregate.GetID(), aggregate.GetVersion())
+	if err != nil {
+		return err
+	}
+	if !found && len(events) == 0 {
+		return ErrAggregateNotFound
+	}
+
+	for _, event := range events {
+		if err := aggregate.RaiseEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PostgresEventStore) loadSnapshot(ctx context.Context, aggregate Aggregate) (bool, error) {
+	var state []byte
+	err := s.pgxPool.QueryRow(ctx,
+		fmt.Sprintf("SELECT state FROM %s_snapshots WHERE aggregate_id = $1", s.table),
+		aggregate.GetID(),
+	).Scan(&state)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, common_utils.Unmarshal(state, aggregate)
+}
+
+// LoadEvents returns the events of the aggregate after fromVersion, upcasted
+// with the default upcasters of the kafka package.
+func (s *PostgresEventStore) LoadEvents(ctx context.Context, aggregateID string, fromVersion uint64) ([]Event, error) {
+	rows, err := s.pgxPool.Query(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE aggregate_id = $1 AND aggregate_version > $2 ORDER BY aggregate_version",
+		eventColumns, s.table,
+	), aggregateID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+// loadEventsAfter returns up to limit events of every aggregate after the
+// global position.
+func (s *PostgresEventStore) loadEventsAfter(ctx context.Context, tx pgx.Tx, position int64, limit int) ([]Event, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE position > $1 ORDER BY position LIMIT $2",
+		eventColumns, s.table,
+	), position, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+func collectEvents(rows pgx.Rows) ([]Event, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+		var event Event
+		err := row.Scan(
+			&event.Position,
+			&event.AggregateID,
+			&event.AggregateType,
+			&event.AggregateVersion,
+			&event.EventID,
+			&event.EventType,
+			&event.Version,
+			&event.Data,
+			&event.Metadata,
+			&event.Timestamp,
+		)
+		if err != nil {
+			return event, err
+		}
+		return event, kafka.Upcast(&event.Event)
+	})
+}
+
+// Exists reports whether events or a snapshot are stored for the aggregate.
+func (s *PostgresEventStore) Exists(ctx context.Context, aggregateID string) (bool, error) {
+	var exists bool
+	err := s.pgxPool.QueryRow(ctx, fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM %[1]s WHERE aggregate_id = $1) OR EXISTS (SELECT 1 FROM %[1]s_snapshots WHERE aggregate_id = $1)",
+		s.table,
+	), aggregateID).Scan(&exists)
+	return exists, err
+}
+
+// Save appends the uncommitted events of the aggregate in a new transaction.
+func (s *PostgresEventStore) Save(ctx context.Context, aggregate Aggregate) error {
+	return common_utils.ExecTx(ctx, s.pgxPool, func(tx pgx.Tx) error {
+		return s.SaveTx(ctx, tx, aggregate)
+	})
+}
+
+// SaveTx appends the uncommitted events of the aggregate inside tx, e.g.
+// together with the outbox. ErrConcurrencyConflict is returned when the
+// aggregate changed since it was loaded, other errors, e.g. a duplicated
+// event ID, are returned as they are. The uncommitted events are cleared once
+// they are written, the snapshot is taken in the same tx.
+//
+// The appends of the store are serialized with a single advisory lock per
+// table, so the positions become visible in order and projections do not
+// skip events. The lock is held until tx ends: the appends of all aggregates
+// wait for each other, and a long tx, e.g. one that also writes the outbox
+// and the business data, delays every other writer of the store. Keep the tx
+// of SaveTx short, the store sustains one append tx at a time.
+func (s *PostgresEventStore) SaveTx(ctx context.Context, tx pgx.Tx, aggregate Aggregate) error {
+	events := aggregate.GetUncommittedEvents()
+	if len(events) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", s.table); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	query := fmt.Sprintf(`
+INSERT INTO %s (aggregate_id, aggregate_type, aggregate_version, event_id, event_type, version, data, metadata, timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, s.table)
+	for _, event := range events {
+		batch.Queue(query,
+			event.AggregateID,
+			event.AggregateType,
+			event.AggregateVersion,
+			event.EventID,
+			event.EventType,
+			event.Version,
+			event.Data,
+			event.Metadata,
+			event.Timestamp,
+		)
+	}
+
+	err := tx.SendBatch(ctx, batch).Close()
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == s.versionConstraint {
+		return fmt.Errorf("%w: %s", ErrConcurrencyConflict, aggregate.GetID())
+	}
+	if err != nil {
+		return err
+	}
+
+	expectedVersion := events[0].AggregateVersion - 1
+	if shouldSnapshot(s.snapshotEvery, expectedVersion, aggregate.GetVersion()) {
+		if err := s.saveSnapshot(ctx, tx, aggregate); err != nil {
+			return err
+		}
+	}
+
+	aggregate.ClearUncommittedEvents()
+	return nil
+}
+
+// shouldSnapshot reports whether the version crossed a multiple of every.
+func shouldSnapshot(every, from, to uint64) bool {
+	return every > 0 && to/every > from/every
+}
+
+func (s *PostgresEventStore) saveSnapshot(ctx context.Context, tx pgx.Tx, aggregate Aggregate) error {
+	state, err := common_utils.Marshal(aggregate)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_snapshots (aggregate_id, aggregate_type, aggregate_version, state) VALUES ($1, $2, $3, $4)
+ON CONFLICT (aggregate_id) DO UPDATE SET
+	aggregate_type = EXCLUDED.aggregate_type,
+	aggregate_version = EXCLUDED.aggregate_version,
+	state = EXCLUDED.state,
+	created_at = now()`, s.table),
+		aggregate.GetID(), aggregate.GetType(), aggregate.GetVersion(), state,
+	)
+	return err
+}
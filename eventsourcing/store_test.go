@@ -0,0 +1,67 @@
+package eventsourcing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dispenal/go-common/kafka"
+	"github.com/dispenal/go-common/postgres/pgtest"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStore migrates an event store with its own tables in the postgres of
+// test.env, the test is skipped when it is not reachable.
+func newTestStore(t *testing.T, config *common_utils.BaseConfig) *PostgresEventStore {
+	pool := pgtest.NewPool(t, "../")
+	config.EventStoreTable = pgtest.TableName(t, pool, "events", "_snapshots", "_projections")
+
+	store := NewEventStore(config, pool)
+	assert.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+func TestPostgresEventStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, &common_utils.BaseConfig{EventStoreSnapshotFreq: 2})
+
+	c := newCounter("counter-1")
+	assert.NoError(t, c.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+	assert.NoError(t, store.Save(ctx, c))
+
+	first, second := newCounter("counter-1"), newCounter("counter-1")
+	assert.NoError(t, store.Load(ctx, first))
+	assert.NoError(t, store.Load(ctx, second))
+	assert.Equal(t, 1, first.Count)
+
+	assert.NoError(t, first.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+	assert.NoError(t, store.Save(ctx, first))
+
+	assert.NoError(t, second.Apply(kafka.NewEvent(counterIncremented, []byte("{}"))))
+	assert.ErrorIs(t, store.Save(ctx, second), ErrConcurrencyConflict)
+	assert.Len(t, second.GetUncommittedEvents(), 1)
+
+	reloaded := newCounter("counter-1")
+	assert.NoError(t, store.Load(ctx, reloaded))
+	assert.Equal(t, 2, reloaded.Count)
+	assert.Equal(t, uint64(2), reloaded.GetVersion())
+
+	exists, err := store.Exists(ctx, "counter-1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.ErrorIs(t, store.Load(ctx, newCounter("counter-2")), ErrAggregateNotFound)
+
+	events, err := store.LoadEvents(ctx, "counter-1", 0)
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, events) {
+		return
+	}
+	duplicated := kafka.NewEvent(counterIncremented, []byte("{}"))
+	duplicated.EventID = events[0].EventID
+	other := newCounter("counter-2")
+	assert.NoError(t, other.Apply(duplicated))
+	err = store.Save(ctx, other)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrConcurrencyConflict)
+}
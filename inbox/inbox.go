@@ -0,0 +1,118 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+)
+
+const defaultRetention = 24 * time.Hour
+
+// EventIDAttribute is the Pub/Sub attribute used as message ID when present.
+const EventIDAttribute = "event_id"
+
+// Inbox skips messages which were already processed successfully by the same
+// consumer, the name given to each wrapped handler. Handlers of the same
+// message need distinct consumer names, otherwise they suppress each other.
+// The check and the record are not atomic: concurrent deliveries of one
+// message may both be processed, handlers must still tolerate rare
+// duplicates.
+type Inbox struct {
+	store     Store
+	retention time.Duration
+}
+
+func NewInbox(config *common_utils.BaseConfig, store Store) *Inbox {
+	retention := config.InboxRetention
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	return &Inbox{store: store, retention: retention}
+}
+
+// process runs f unless consumer processed id, it reports whether id was
+// skipped.
+func (i *Inbox) process(ctx context.Context, consumer, id string, f func() error) (bool, error) {
+	processed, err := i.store.IsProcessed(ctx, consumer, id)
+	if err != nil {
+		return false, err
+	}
+	if processed {
+		common_utils.LogInfo(fmt.Sprintf("skip message already processed by %s: %s", consumer, id))
+		return true, nil
+	}
+
+	if err := f(); err != nil {
+		return false, err
+	}
+
+	if err := i.store.MarkProcessed(ctx, consumer, id, i.retention); err != nil {
+		common_utils.LogError(fmt.Sprintf("failed mark message as processed: %s, error: %v", id, err))
+	}
+	return false, nil
+}
+
+// KafkaHandler deduplicates by the EventID of the Event envelope, messages
+// which are no Event fall back to their original topic, partition and offset.
+// Duplicates are committed without calling f.
+func (i *Inbox) KafkaHandler(consumer string, f kafka.HandlerFunc) kafka.HandlerFunc {
+	return func(ctx context.Context, msg *kafka.Message) error {
+		skipped, err := i.process(ctx, consumer, kafkaMessageID(msg), func() error {
+			return f(ctx, msg)
+		})
+		if skipped && msg.Commit != nil {
+			return msg.Commit()
+		}
+		return err
+	}
+}
+
+func kafkaMessageID(msg *kafka.Message) string {
+	event := kafka.Event{}
+	if err := common_utils.Unmarshal(msg.Body, &event); err == nil && event.EventID != "" {
+		return event.EventID
+	}
+
+	topic, partition, offset := msg.Topic, fmt.Sprint(msg.Partition), fmt.Sprint(msg.Offset)
+	if value, ok := msg.Headers[kafka.HeaderOriginalPartition]; ok {
+		partition = value
+	}
+	if value, ok := msg.Headers[kafka.HeaderOriginalOffset]; ok {
+		offset = value
+	}
+	return fmt.Sprintf("%s/%s/%s", topic, partition, offset)
+}
+
+// PubSubMiddleware deduplicates by the event_id attribute, or the message ID
+// when it is missing. Duplicates return nil without calling f.
+func (i *Inbox) PubSubMiddleware(consumer string, f func(ctx context.Context, msg *pubsub.Message) error) func(ctx context.Context, msg *pubsub.Message) error {
+	return func(ctx context.Context, msg *pubsub.Message) error {
+		id := msg.ID
+		if eventID := msg.Attributes[EventIDAttribute]; eventID != "" {
+			id = eventID
+		}
+
+		_, err := i.process(ctx, consumer, id, func() error {
+			return f(ctx, msg)
+		})
+		return err
+	}
+}
+
+// PubSubHandler wraps f into a PullMessages callback, the message is acked
+// when f succeeds or it is a duplicate, and nacked otherwise.
+func (i *Inbox) PubSubHandler(consumer string, f func(ctx context.Context, msg *pubsub.Message) error) func(ctx context.Context, msg *pubsub.Message) {
+	handle := i.PubSubMiddleware(consumer, f)
+	return func(ctx context.Context, msg *pubsub.Message) {
+		if err := handle(ctx, msg); err != nil {
+			common_utils.LogError(fmt.Sprintf("failed process message %s: %v", msg.ID, err))
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+	}
+}
@@ -0,0 +1,141 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	processed map[string]time.Duration
+}
+
+func (s *memoryStore) IsProcessed(ctx context.Context, consumer, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.processed[consumer+"/"+id]
+	return ok, nil
+}
+
+func (s *memoryStore) MarkProcessed(ctx context.Context, consumer, id string, retention time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processed[consumer+"/"+id] = retention
+	return nil
+}
+
+func newTestInbox() (*Inbox, *memoryStore) {
+	store := &memoryStore{processed: make(map[string]time.Duration)}
+	return NewInbox(&common_utils.BaseConfig{InboxRetention: time.Hour}, store), store
+}
+
+func TestKafkaHandler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Skip and commit duplicated event", func(t *testing.T) {
+		inbox, store := newTestInbox()
+
+		calls, commits := 0, 0
+		handler := inbox.KafkaHandler("tester", func(ctx context.Context, msg *kafka.Message) error {
+			calls++
+			return nil
+		})
+
+		body, _ := common_utils.Marshal(kafka.NewEvent("user.signed_up", []byte("{}")))
+		msg := &kafka.Message{Body: body, Commit: func() error {
+			commits++
+			return nil
+		}}
+
+		assert.NoError(t, handler(ctx, msg))
+		assert.NoError(t, handler(ctx, msg))
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 1, commits)
+		assert.Len(t, store.processed, 1)
+	})
+
+	t.Run("Process the event once per consumer", func(t *testing.T) {
+		inbox, store := newTestInbox()
+
+		calls := make(map[string]int)
+		newHandler := func(consumer string) kafka.HandlerFunc {
+			return inbox.KafkaHandler(consumer, func(ctx context.Context, msg *kafka.Message) error {
+				calls[consumer]++
+				return nil
+			})
+		}
+		welcome, audit := newHandler("welcome"), newHandler("audit")
+
+		body, _ := common_utils.Marshal(kafka.NewEvent("user.signed_up", []byte("{}")))
+		msg := &kafka.Message{Body: body}
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, welcome(ctx, msg))
+			assert.NoError(t, audit(ctx, msg))
+		}
+
+		assert.Equal(t, map[string]int{"welcome": 1, "audit": 1}, calls)
+		assert.Len(t, store.processed, 2)
+	})
+
+	t.Run("Do not record failed messages", func(t *testing.T) {
+		inbox, store := newTestInbox()
+		handler := inbox.KafkaHandler("tester", func(ctx context.Context, msg *kafka.Message) error {
+			return errors.New("failed")
+		})
+
+		err := handler(ctx, &kafka.Message{Topic: "tester", Offset: 1, Body: []byte("raw")})
+
+		assert.Error(t, err)
+		assert.Empty(t, store.processed)
+	})
+
+	t.Run("Use original position for messages without event", func(t *testing.T) {
+		msg := &kafka.Message{
+			Topic:     "tester",
+			Partition: 0,
+			Offset:    3,
+			Headers: map[string]string{
+				kafka.HeaderOriginalPartition: "2",
+				kafka.HeaderOriginalOffset:    "41",
+			},
+		}
+		assert.Equal(t, "tester/2/41", kafkaMessageID(msg))
+	})
+}
+
+func TestPubSubMiddleware(t *testing.T) {
+	ctx := context.Background()
+	inbox, store := newTestInbox()
+
+	calls := 0
+	handler := inbox.PubSubMiddleware("tester", func(ctx context.Context, msg *pubsub.Message) error {
+		calls++
+		return nil
+	})
+
+	first := &pubsub.Message{ID: "1", Attributes: map[string]string{EventIDAttribute: "event-1"}}
+	redelivered := &pubsub.Message{ID: "2", Attributes: map[string]string{EventIDAttribute: "event-1"}}
+
+	assert.NoError(t, handler(ctx, first))
+	assert.NoError(t, handler(ctx, redelivered))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, time.Hour, store.processed["tester/event-1"])
+}
+
+func TestNewPostgresStore(t *testing.T) {
+	assert.Equal(t, defaultTable, NewPostgresStore(&common_utils.BaseConfig{}, nil).table)
+	assert.Equal(t, "app.inbox", NewPostgresStore(&common_utils.BaseConfig{InboxTable: "app.inbox"}, nil).table)
+	assert.Panics(t, func() {
+		NewPostgresStore(&common_utils.BaseConfig{InboxTable: "inbox; DROP TABLE users"}, nil)
+	})
+}
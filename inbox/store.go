@@ -0,0 +1,110 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	redis_client "github.com/dispenal/go-common/redis"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultTable = "inbox"
+
+// Store records the IDs of the messages processed by each consumer for a
+// retention window.
+type Store interface {
+	IsProcessed(ctx context.Context, consumer, id string) (bool, error)
+	MarkProcessed(ctx context.Context, consumer, id string, retention time.Duration) error
+}
+
+type RedisStore struct {
+	config   *common_utils.BaseConfig
+	cacheSvc redis_client.CacheSvc
+}
+
+// NewRedisStore records processed IDs with cacheSvc, the keys expire after
+// the retention window.
+func NewRedisStore(config *common_utils.BaseConfig, cacheSvc redis_client.CacheSvc) Store {
+	return &RedisStore{config: config, cacheSvc: cacheSvc}
+}
+
+func (s *RedisStore) key(consumer, id string) string {
+	return common_utils.BuildPrefixKey("inbox", s.config.ServiceName, consumer, id)
+}
+
+func (s *RedisStore) IsProcessed(ctx context.Context, consumer, id string) (bool, error) {
+	var processed bool
+	err := s.cacheSvc.Get(ctx, s.key(consumer, id), &processed)
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return processed, nil
+}
+
+func (s *RedisStore) MarkProcessed(ctx context.Context, consumer, id string, retention time.Duration) error {
+	return s.cacheSvc.Set(ctx, s.key(consumer, id), true, retention)
+}
+
+type PostgresStore struct {
+	table   string
+	pgxPool *pgxpool.Pool
+}
+
+// NewPostgresStore records processed IDs in the INBOX_TABLE table, call
+// Migrate to create it and Purge to delete expired IDs.
+func NewPostgresStore(config *common_utils.BaseConfig, pgxPool *pgxpool.Pool) *PostgresStore {
+	table := config.InboxTable
+	if table == "" {
+		table = defaultTable
+	}
+	if !common_utils.ValidTableName(table) {
+		common_utils.PanicAppError(fmt.Sprintf("invalid inbox table name: %s", table), 500)
+	}
+	return &PostgresStore{table: table, pgxPool: pgxPool}
+}
+
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	_, err := s.pgxPool.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	consumer     TEXT NOT NULL,
+	id           TEXT NOT NULL,
+	processed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at   TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (consumer, id)
+)`, s.table))
+	return err
+}
+
+func (s *PostgresStore) IsProcessed(ctx context.Context, consumer, id string) (bool, error) {
+	var processed bool
+	err := s.pgxPool.QueryRow(ctx,
+		fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE consumer = $1 AND id = $2 AND expires_at > now())", s.table),
+		consumer, id,
+	).Scan(&processed)
+	return processed, err
+}
+
+func (s *PostgresStore) MarkProcessed(ctx context.Context, consumer, id string, retention time.Duration) error {
+	_, err := s.pgxPool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s (consumer, id, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (consumer, id) DO UPDATE SET processed_at = now(), expires_at = EXCLUDED.expires_at`, s.table),
+		consumer, id, time.Now().Add(retention),
+	)
+	return err
+}
+
+// Purge deletes the IDs whose retention window passed.
+func (s *PostgresStore) Purge(ctx context.Context) (int64, error) {
+	tag, err := s.pgxPool.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE expires_at <= now()", s.table))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
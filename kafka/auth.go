@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const (
+	SaslPlain       = "PLAIN"
+	SaslScramSha256 = "SCRAM-SHA-256"
+	SaslScramSha512 = "SCRAM-SHA-512"
+)
+
+// connAuth is applied to every connection of the client: readers, writers,
+// the DLQ reader and the admin client.
+type connAuth struct {
+	mechanism sasl.Mechanism
+	tls       *tls.Config
+}
+
+func newConnAuth(cfg *common_utils.BaseConfig) (connAuth, error) {
+	mechanism, err := newSASLMechanism(cfg)
+	if err != nil {
+		return connAuth{}, err
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return connAuth{}, err
+	}
+
+	return connAuth{mechanism: mechanism, tls: tlsConfig}, nil
+}
+
+func newSASLMechanism(cfg *common_utils.BaseConfig) (sasl.Mechanism, error) {
+	switch strings.ToUpper(cfg.KafkaSaslMechanism) {
+	case "":
+		return nil, nil
+	case SaslPlain:
+		return plain.Mechanism{Username: cfg.KafkaSaslUsername, Password: cfg.KafkaSaslPassword}, nil
+	case SaslScramSha256:
+		return scram.Mechanism(scram.SHA256, cfg.KafkaSaslUsername, cfg.KafkaSaslPassword)
+	case SaslScramSha512:
+		return scram.Mechanism(scram.SHA512, cfg.KafkaSaslUsername, cfg.KafkaSaslPassword)
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism: %s", cfg.KafkaSaslMechanism)
+	}
+}
+
+func newTLSConfig(cfg *common_utils.BaseConfig) (*tls.Config, error) {
+	return common_utils.NewTLSConfig(common_utils.TLSOptions{
+		Enable:     cfg.KafkaTlsEnable,
+		SkipVerify: cfg.KafkaTlsSkipVerify,
+		CaFile:     cfg.KafkaTlsCaFile,
+		CertFile:   cfg.KafkaTlsCertFile,
+		KeyFile:    cfg.KafkaTlsKeyFile,
+	})
+}
+
+func (k *Client) newDialer() *kafka.Dialer {
+	return &kafka.Dialer{
+		Timeout:       3 * time.Second,
+		DualStack:     true,
+		KeepAlive:     5 * time.Second,
+		ClientID:      RandStringBytes(5),
+		SASLMechanism: k.auth.mechanism,
+		TLS:           k.auth.tls,
+	}
+}
+
+func (k *Client) newTransport() *kafka.Transport {
+	return &kafka.Transport{
+		DialTimeout: 5 * time.Second,
+		SASL:        k.auth.mechanism,
+		TLS:         k.auth.tls,
+	}
+}
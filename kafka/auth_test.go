@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSASLMechanism(t *testing.T) {
+	mechanism, err := newSASLMechanism(&common_utils.BaseConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, mechanism)
+
+	for _, name := range []string{SaslPlain, SaslScramSha256, "scram-sha-512"} {
+		mechanism, err := newSASLMechanism(&common_utils.BaseConfig{
+			KafkaSaslMechanism: name,
+			KafkaSaslUsername:  "user",
+			KafkaSaslPassword:  "secret",
+		})
+		assert.NoError(t, err)
+		assert.NotNil(t, mechanism)
+	}
+
+	_, err = newSASLMechanism(&common_utils.BaseConfig{KafkaSaslMechanism: "GSSAPI"})
+	assert.Error(t, err)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConfig, err := newTLSConfig(&common_utils.BaseConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = newTLSConfig(&common_utils.BaseConfig{KafkaTlsEnable: true, KafkaTlsSkipVerify: true})
+	assert.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = newTLSConfig(&common_utils.BaseConfig{KafkaTlsEnable: true, KafkaTlsCaFile: "does-not-exist.pem"})
+	assert.Error(t, err)
+}
+
+func TestNewKafkaClientWithAuth(t *testing.T) {
+	cfg := &common_utils.BaseConfig{KafkaSaslMechanism: "GSSAPI"}
+
+	_, err := NewKafkaClientWithAuth(cfg)
+	assert.Error(t, err)
+	assert.NotPanics(t, func() { NewKafkaClient(cfg) })
+
+	client, err := NewKafkaClientWithAuth(&common_utils.BaseConfig{KafkaSaslMechanism: SaslPlain})
+	assert.NoError(t, err)
+	assert.NotNil(t, client.(*Client).auth.mechanism)
+}
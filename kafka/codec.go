@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"fmt"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes the Data of an Event. The Event envelope itself is
+// always json, as written by Publish.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes the data with common_utils.Marshal.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return common_utils.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return common_utils.Unmarshal(data, v)
+}
+
+// ProtoCodec encodes the data with protobuf, values must implement
+// proto.Message.
+type ProtoCodec struct{}
+
+func (ProtoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto codec: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto codec: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// CodecFuncs adapts a pair of functions to a Codec, e.g. to plug an Avro
+// library:
+//
+//	kafka.CodecFuncs{
+//		MarshalFunc:   func(v any) ([]byte, error) { return avro.Marshal(schema, v) },
+//		UnmarshalFunc: func(data []byte, v any) error { return avro.Unmarshal(schema, data, v) },
+//	}
+type CodecFuncs struct {
+	MarshalFunc   func(v any) ([]byte, error)
+	UnmarshalFunc func(data []byte, v any) error
+}
+
+func (c CodecFuncs) Marshal(v any) ([]byte, error) {
+	return c.MarshalFunc(v)
+}
+
+func (c CodecFuncs) Unmarshal(data []byte, v any) error {
+	return c.UnmarshalFunc(data, v)
+}
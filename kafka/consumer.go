@@ -14,145 +14,264 @@ import (
 )
 
 func (k *Client) NewConsumer() {
-	batchSize := int(10e6) // 10MB
-	dialer := &kafka.Dialer{
-		Timeout:   3 * time.Second,
-		DualStack: true,
-		KeepAlive: 5 * time.Second,
-		ClientID:  RandStringBytes(5),
-	}
-	k.readers = make(map[string]*kafka.Reader)
+	dialer := k.newDialer()
+	k.readers = make(map[string]messageReader)
+	k.retryDelays = make(map[string]time.Duration)
 	for _, topic := range k.cfg.KafkaTopics {
-		r := kafka.NewReader(kafka.ReaderConfig{
-			Brokers:  k.cfg.KafkaBrokers,
-			GroupID:  k.cfg.KafkaGroupID,
-			Topic:    topic,
-			Dialer:   dialer,
-			MaxBytes: batchSize,
-		})
-		if r == nil {
-			common_utils.LogError("empty reader, please check kafka connection")
+		k.readers[topic] = k.newTopicReader(topic, dialer)
+
+		config := k.consumerConfig(topic)
+		for _, tier := range k.retryTiers {
+			retryTopic, ok := k.retryTopic(topic, tier)
+			if !ok {
+				break
+			}
+			k.readers[retryTopic] = k.newReader(retryTopic, config, dialer)
+			k.retryDelays[retryTopic] = tier.delay
 		}
-		common_utils.LogInfo(fmt.Sprintf("Listen: %s, %d, [%s]", r.Stats().Partition, r.Stats().QueueCapacity, r.Stats().Topic))
-		k.readers[topic] = r
 	}
 }
 
+func (k *Client) newReader(topic string, config ConsumerConfig, dialer *kafka.Dialer) *kafka.Reader {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     k.cfg.KafkaBrokers,
+		GroupID:     config.GroupID,
+		Topic:       topic,
+		Dialer:      dialer,
+		MaxBytes:    readerMaxBytes,
+		StartOffset: config.StartOffset,
+	})
+	if r == nil {
+		common_utils.LogError("empty reader, please check kafka connection")
+	}
+	common_utils.LogInfo(fmt.Sprintf("Listen: %s, %d, [%s]", r.Stats().Partition, r.Stats().QueueCapacity, r.Stats().Topic))
+	return r
+}
+
 func (k *Client) IsWriters() bool {
-	return k.writer != nil
+	return k.writer.Load() != nil
 }
+
+// Close stops every listener, waits for in-flight messages up to
+// KafkaDrainTimeout and then closes the readers.
 func (k *Client) Close() error {
+	return k.Shutdown(context.Background())
+}
+
+// Shutdown stops fetching new messages, waits until every in-flight handler
+// finished (and committed) or the drain timeout/ctx expires, and only then
+// closes the readers. Messages that were not committed are redelivered.
+func (k *Client) Shutdown(ctx context.Context) error {
+	k.mu.Lock()
+	for _, cancel := range k.cancels {
+		cancel()
+	}
+	k.cancels = nil
+	k.mu.Unlock()
+
+	k.fetchers.Wait()
+
+	drainTimeout := k.cfg.KafkaDrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		k.inflight.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-drainCtx.Done():
+		common_utils.LogError("kafka drain timeout, closing readers with in-flight messages")
+		err = ErrDrainTimeout
+	}
+
 	for _, r := range k.readers {
-		r.Close()
+		common_utils.LogIfError(r.Close())
 	}
-	return nil
+	return err
 }
 
-func (k *Client) handleMessage(ctx context.Context, r *kafka.Reader, m kafka.Message, f HandlerFunc) {
-	retries := 1
-	errorMsg := ""
+func (k *Client) handleMessage(ctx context.Context, tracker *offsetTracker, offset *pendingOffset, f HandlerFunc) {
+	m := offset.msg
 
+	// handlers and commits must not be aborted by a shutdown, only new
+	// fetches are.
+	handlerCtx := context.WithoutCancel(ctx)
+
+	attempt := retryAttempt(m)
 	headers := tracer.TextMapCarrierFromKafkaMessageHeaders(m.Headers)
 
 	msg := &Message{
 		Offset:    m.Offset,
 		Partition: m.Partition,
-		Topic:     m.Topic,
+		Topic:     originalTopic(m),
 		Headers:   headers,
 		Body:      m.Value,
 		Timestamp: m.Time.Unix(),
 		Key:       string(m.Key),
-		Retry:     retries,
+		Retry:     attempt,
 		Commit: func() error {
-			if err := r.CommitMessages(ctx, m); err != nil {
-				return err
-			}
-			return nil
+			return tracker.commit(handlerCtx, offset)
 		},
 		MoveToDLQ: func() error {
-			return k.publishToDLQ(ctx, m)
+			return k.publishToDLQ(handlerCtx, m)
 		},
 	}
 
-	for {
-		if retries > k.cfg.KafkaDlqRetry {
-			spanCtx, span := tracer.StartAndTraceKafkaConsumer(ctx, headers, "kafkaConsumer.publishToDLQ")
-			span.RecordError(errors.New(errorMsg))
-			span.SetStatus(codes.Error, errorMsg)
-			span.SetAttributes(tracer.BuildAttribute(msg)...)
+	body, schemaID, err := k.schemas.decode(handlerCtx, m.Value)
+	if err != nil {
+		k.moveToDLQ(handlerCtx, tracker, offset, msg, err)
+		return
+	}
+	msg.Body = body
+	msg.SchemaID = schemaID
 
-			common_utils.LogError(fmt.Sprintf("failed process message: %s, will move to DLQ", string(m.Key)))
+	start := time.Now()
+	err = f(handlerCtx, msg)
+	k.metrics.handle(handlerCtx, m, start, err)
+	if err == nil {
+		complete := tracker.done
+		if k.cfg.KafkaAutoCommit {
+			complete = tracker.commit
+		}
+		if err := complete(handlerCtx, offset); err != nil {
+			common_utils.LogError(fmt.Sprintf("failed commit message: %s, error: %v", string(m.Key), err))
+		}
+		return
+	}
 
-			m.Headers = append(m.Headers, kafka.Header{
-				Key:   "error",
-				Value: []byte(errorMsg),
-			})
+	var permanent *PermanentError
+	if attempt < k.cfg.KafkaDlqRetry && !errors.As(err, &permanent) && !k.consumerConfig(msg.Topic).GroupLess {
+		common_utils.LogError(fmt.Sprintf("failed process message %s with error %v, will retry %d/%d", string(m.Key), err, attempt, k.cfg.KafkaDlqRetry))
 
-			if err := k.publishToDLQ(spanCtx, m); err != nil {
-				tracer.TraceErr(spanCtx, err)
-				common_utils.LogError(fmt.Sprintf("failed move message to DLQ: %s", string(m.Key)))
+		retryErr := k.publishToRetry(handlerCtx, m, attempt+1, err)
+		if retryErr == nil {
+			k.metrics.retry(handlerCtx, m)
+			if err := tracker.commit(handlerCtx, offset); err != nil {
+				common_utils.LogError(fmt.Sprintf("failed commit message after publish retry: %s", string(m.Key)))
 			}
+			return
+		}
+		common_utils.LogError(fmt.Sprintf("failed publish message %s to retry topic: %v", string(m.Key), retryErr))
+	}
 
-			if err := r.CommitMessages(spanCtx, m); err != nil {
-				tracer.TraceErr(spanCtx, err)
-				common_utils.LogError(fmt.Sprintf("failed commit message after publish DLQ: %s", string(m.Key)))
-			}
-			span.End()
+	k.moveToDLQ(handlerCtx, tracker, offset, msg, err)
+}
 
-			break
-		}
+func (k *Client) moveToDLQ(ctx context.Context, tracker *offsetTracker, offset *pendingOffset, msg *Message, cause error) {
+	m := offset.msg
 
-		if err := f(ctx, msg); err != nil {
-			common_utils.LogError(fmt.Sprintf("failed process message %s with error %v, will retry %d/%d", string(m.Key), err, retries, k.cfg.KafkaDlqRetry))
-			errorMsg = err.Error()
-			time.Sleep(k.Backoff.NextBackOff())
-			retries++
-			msg.Retry = retries
-			continue
-		}
-		break
+	spanCtx, span := tracer.StartAndTraceKafkaConsumer(ctx, msg.Headers, "kafkaConsumer.publishToDLQ")
+	defer span.End()
+
+	span.RecordError(cause)
+	span.SetStatus(codes.Error, cause.Error())
+	span.SetAttributes(tracer.BuildAttribute(msg)...)
+
+	common_utils.LogError(fmt.Sprintf("failed process message: %s, will move to DLQ", string(m.Key)))
+
+	m.Headers = setHeader(m.Headers, HeaderError, cause.Error())
+
+	if err := k.publishToDLQ(spanCtx, m); err != nil {
+		tracer.TraceErr(spanCtx, err)
+		common_utils.LogError(fmt.Sprintf("failed move message to DLQ: %s", string(m.Key)))
+	} else {
+		k.metrics.moveToDLQ(spanCtx, m)
+	}
+
+	if err := tracker.commit(spanCtx, offset); err != nil {
+		tracer.TraceErr(spanCtx, err)
+		common_utils.LogError(fmt.Sprintf("failed commit message after publish DLQ: %s", string(m.Key)))
 	}
 }
 
-// Listen manual listen
-// need call msg.Commit() when process done
-// recommend for this process
+// Listen hands the messages of every topic and its retry topics to f. A
+// message is completed when f returns nil and committed together with the
+// next commit of its partition, or right away with KAFKA_AUTO_COMMIT. Calling
+// msg.Commit() in f requests the commit before f returns, it is optional and
+// can be called more than once. A failed message is retried or moved to the
+// DLQ.
 func (k *Client) Listen(f HandlerFunc) error {
-	for _, r := range k.readers {
-		ctx := context.Background()
-
-		go func(r *kafka.Reader) {
-			for {
-				m, err := r.FetchMessage(ctx) // is not auto commit
-				if err != nil && errors.Is(err, io.ErrUnexpectedEOF) {
-					break
-				}
-				if err != nil && errors.Is(err, io.EOF) {
-					break
-				}
-				if err != nil {
-					common_utils.LogIfError(err)
-					continue
-				}
-				go k.handleMessage(ctx, r, m, f)
+	return k.ListenWithContext(context.Background(), f)
+}
 
-			}
-		}(r)
+// ListenWithContext works like Listen, but stops fetching from every reader
+// once ctx is cancelled. Call Shutdown (or Close) afterwards to drain the
+// in-flight messages before the readers are closed.
+func (k *Client) ListenWithContext(ctx context.Context, f HandlerFunc) error {
+	ctx = k.listenContext(ctx)
+	for _, r := range k.readers {
+		k.fetch(ctx, r, "", f)
 	}
 	return nil
 }
 
+// ListenTopic works like Listen for topic and its retry topics only.
 func (k *Client) ListenTopic(topic string, f HandlerFunc) error {
-	ctx := context.Background()
+	return k.ListenTopicWithContext(context.Background(), topic, f)
+}
 
+// ListenTopicWithContext works like ListenTopic, but stops fetching once ctx
+// is cancelled.
+func (k *Client) ListenTopicWithContext(ctx context.Context, topic string, f HandlerFunc) error {
 	r := k.readers[topic]
 	if r == nil {
 		common_utils.LogError(fmt.Sprintf("listen topic %s not found", topic))
 		return errors.New("listen topic not found")
 	}
-	go func(r *kafka.Reader) {
+
+	ctx = k.listenContext(ctx)
+	k.fetch(ctx, r, topic, f)
+	for _, tier := range k.retryTiers {
+		retryTopic, ok := k.retryTopic(topic, tier)
+		if !ok {
+			break
+		}
+		if retryReader := k.readers[retryTopic]; retryReader != nil {
+			k.fetch(ctx, retryReader, "", f)
+		}
+	}
+	return nil
+}
+
+// listenContext derives a context that is cancelled by Shutdown as well.
+func (k *Client) listenContext(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	k.mu.Lock()
+	k.cancels = append(k.cancels, cancel)
+	k.mu.Unlock()
+
+	return ctx
+}
+
+func (k *Client) fetch(ctx context.Context, r messageReader, topic string, f HandlerFunc) {
+	tracker := newOffsetTracker(r)
+	d := newDispatcher(k.cfg.KafkaConcurrency, k.cfg.KafkaOrdering, func(j job) {
+		defer k.inflight.Done()
+		if ctx.Err() != nil {
+			// queued but not started, left uncommitted for redelivery
+			return
+		}
+		k.handleMessage(ctx, tracker, j.offset, f)
+	})
+
+	k.fetchers.Add(1)
+	go func() {
+		defer k.fetchers.Done()
+		defer d.close()
 		for {
 			m, err := r.FetchMessage(ctx) // is not auto commit
+			if ctx.Err() != nil {
+				break
+			}
 			if err != nil && errors.Is(err, io.ErrUnexpectedEOF) {
 				break
 			}
@@ -164,10 +283,20 @@ func (k *Client) ListenTopic(topic string, f HandlerFunc) error {
 				continue
 			}
 
-			if m.Topic == topic {
-				go k.handleMessage(ctx, r, m, f)
+			if topic != "" && m.Topic != topic {
+				continue
+			}
+			k.metrics.fetch(ctx, m)
+
+			if delay, ok := k.retryDelays[m.Topic]; ok && !waitRetryDelay(ctx, m, delay) {
+				break
+			}
+
+			k.inflight.Add(1)
+			if !d.dispatch(ctx, job{msg: m, offset: tracker.track(m)}) {
+				k.inflight.Done()
+				break
 			}
 		}
-	}(r)
-	return nil
+	}()
 }
@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"context"
+	"time"
+)
+
+// RebalanceFunc is called with the partitions of topic assigned to or revoked
+// from this instance.
+type RebalanceFunc func(ctx context.Context, topic string, partitions []int)
+
+// ConsumerConfig configures the consumer of a single topic, topics without
+// config are consumed by the KafkaGroupID group from the first offset.
+type ConsumerConfig struct {
+	// GroupID overrides KafkaGroupID for the topic and its retry topics, the
+	// retry topics of the group are named by GroupRetryTopic.
+	GroupID string
+	// StartOffset is where a group without committed offset starts,
+	// kafka.FirstOffset (default) or kafka.LastOffset. Group-less readers
+	// accept any offset.
+	StartOffset int64
+	// GroupLess reads the topic without consumer group, e.g. to replay a
+	// range: every instance reads every message and nothing is committed.
+	// Failed messages are not retried, they are moved to the DLQ.
+	GroupLess bool
+	// Partitions limits the partitions of a group-less reader, all by
+	// default.
+	Partitions []int
+	// StartTime starts a group-less reader at the first message written at or
+	// after StartTime instead of StartOffset. Use ResetOffsetsToTime to move a
+	// consumer group.
+	StartTime time.Time
+	// Until stops a group-less reader at the last message written before
+	// Until, the topic is read without end when zero. With an Until in the
+	// past a partition also stops at its end offset when the reader started.
+	Until time.Time
+	// OnAssigned and OnRevoked are called on every rebalance of the group,
+	// e.g. to load or flush state of the partitions. OnRevoked is called
+	// once fetching of the revoked partitions stopped, commits of the revoked
+	// partitions fail afterwards.
+	OnAssigned RebalanceFunc
+	OnRevoked  RebalanceFunc
+}
+
+// ConfigureTopic sets the consumer config of topic, it must be called before
+// NewConsumer.
+func (k *Client) ConfigureTopic(topic string, config ConsumerConfig) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.consumerConfigs == nil {
+		k.consumerConfigs = make(map[string]ConsumerConfig)
+	}
+	k.consumerConfigs[topic] = config
+}
+
+func (k *Client) consumerConfig(topic string) ConsumerConfig {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	config := k.consumerConfigs[topic]
+	if config.GroupID == "" {
+		config.GroupID = k.cfg.KafkaGroupID
+	}
+	return config
+}
@@ -0,0 +1,41 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownDrain(t *testing.T) {
+	cfg := &common_utils.BaseConfig{KafkaDrainTimeout: 50 * time.Millisecond}
+
+	t.Run("Wait for in-flight handlers", func(t *testing.T) {
+		client := NewKafkaClient(cfg).(*Client)
+		client.inflight.Add(1)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			client.inflight.Done()
+		}()
+
+		assert.NoError(t, client.Shutdown(context.Background()))
+	})
+
+	t.Run("Return drain timeout when handlers are stuck", func(t *testing.T) {
+		client := NewKafkaClient(cfg).(*Client)
+		client.inflight.Add(1)
+		defer client.inflight.Done()
+
+		assert.ErrorIs(t, client.Shutdown(context.Background()), ErrDrainTimeout)
+	})
+
+	t.Run("Cancel listener context", func(t *testing.T) {
+		client := NewKafkaClient(cfg).(*Client)
+		ctx := client.listenContext(context.Background())
+
+		assert.NoError(t, client.Shutdown(context.Background()))
+		assert.Error(t, ctx.Err())
+	})
+}
@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// OrderByPartition processes the messages of a partition sequentially.
+	OrderByPartition = "partition"
+	// OrderByKey processes the messages with the same key sequentially, so
+	// different keys of one partition may run in parallel.
+	OrderByKey = "key"
+)
+
+const (
+	defaultConcurrency = 10
+	workerQueueSize    = 16
+)
+
+type job struct {
+	msg    kafka.Message
+	offset *pendingOffset
+}
+
+// dispatcher is a bounded worker pool. Messages are routed to a worker by
+// partition (or key), every worker runs its messages one by one, so ordering
+// is kept while different partitions are processed in parallel.
+type dispatcher struct {
+	ordering string
+	workers  []chan job
+}
+
+func newDispatcher(concurrency int, ordering string, handle func(job)) *dispatcher {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	d := &dispatcher{
+		ordering: ordering,
+		workers:  make([]chan job, concurrency),
+	}
+	for i := range d.workers {
+		ch := make(chan job, workerQueueSize)
+		d.workers[i] = ch
+
+		go func() {
+			for j := range ch {
+				handle(j)
+			}
+		}()
+	}
+	return d
+}
+
+// dispatch blocks while the selected worker queue is full, it returns false
+// when ctx is done before the job was queued.
+func (d *dispatcher) dispatch(ctx context.Context, j job) bool {
+	select {
+	case d.workers[d.worker(j.msg)] <- j:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (d *dispatcher) worker(m kafka.Message) int {
+	if d.ordering == OrderByKey && len(m.Key) > 0 {
+		h := fnv.New32a()
+		h.Write(m.Key)
+		return int(h.Sum32() % uint32(len(d.workers)))
+	}
+	return m.Partition % len(d.workers)
+}
+
+// close stops the workers once their queues are empty, it must be called by
+// the goroutine calling dispatch.
+func (d *dispatcher) close() {
+	for _, ch := range d.workers {
+		close(ch)
+	}
+}
@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher(t *testing.T) {
+	t.Run("Keep order of messages in the same partition", func(t *testing.T) {
+		var (
+			mu   sync.Mutex
+			wg   sync.WaitGroup
+			seen = map[int][]int64{}
+		)
+
+		d := newDispatcher(4, OrderByPartition, func(j job) {
+			defer wg.Done()
+			mu.Lock()
+			seen[j.msg.Partition] = append(seen[j.msg.Partition], j.msg.Offset)
+			mu.Unlock()
+		})
+
+		for offset := int64(0); offset < 50; offset++ {
+			for partition := 0; partition < 3; partition++ {
+				wg.Add(1)
+				d.dispatch(context.Background(), job{msg: kafka.Message{Partition: partition, Offset: offset}})
+			}
+		}
+		d.close()
+		wg.Wait()
+
+		for partition := 0; partition < 3; partition++ {
+			assert.Len(t, seen[partition], 50)
+			assert.IsIncreasing(t, seen[partition])
+		}
+	})
+
+	t.Run("Route messages with the same key to the same worker", func(t *testing.T) {
+		d := newDispatcher(8, OrderByKey, func(j job) {})
+		defer d.close()
+
+		first := d.worker(kafka.Message{Partition: 0, Key: []byte("aggregate-1")})
+		second := d.worker(kafka.Message{Partition: 5, Key: []byte("aggregate-1")})
+
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("Stop dispatching when context is done", func(t *testing.T) {
+		block := make(chan struct{})
+		d := newDispatcher(1, OrderByPartition, func(j job) { <-block })
+		defer close(block)
+		defer d.close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		ok := true
+		for i := 0; i < workerQueueSize+2 && ok; i++ {
+			ok = d.dispatch(ctx, job{})
+		}
+		assert.False(t, ok)
+	})
+}
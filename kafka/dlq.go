@@ -0,0 +1,332 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+const defaultDLQMaxReplay = 3
+
+// ErrReplayLimitReached is returned when a DLQ message was already replayed
+// KafkaDlqMaxReplay times.
+var ErrReplayLimitReached = errors.New("dlq replay limit reached")
+
+// DLQMessage is a message read from KafkaDlqTopic. Partition and Offset are
+// the position in the DLQ, the Original fields the position where the message
+// failed.
+type DLQMessage struct {
+	Partition         int
+	Offset            int64
+	Key               string
+	Value             []byte
+	Headers           []kafka.Header
+	OriginalTopic     string
+	OriginalPartition int
+	OriginalOffset    int64
+	Error             string
+	// Origin is the service which published the message, FailedBy the
+	// service which moved it to the DLQ.
+	Origin      string
+	FailedBy    string
+	ReplayCount int
+	Timestamp   time.Time
+	// Replayed is set when the message was replayed by ReplayDLQ before.
+	Replayed bool
+}
+
+// DLQFilter selects DLQ messages, zero values match everything except the
+// messages replayed before.
+type DLQFilter struct {
+	Topic           string
+	From            time.Time
+	To              time.Time
+	ErrorContains   string
+	Limit           int
+	IncludeReplayed bool
+}
+
+func (f DLQFilter) match(m DLQMessage) bool {
+	if m.Replayed && !f.IncludeReplayed {
+		return false
+	}
+	if f.Topic != "" && m.OriginalTopic != f.Topic {
+		return false
+	}
+	if !f.From.IsZero() && m.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && m.Timestamp.After(f.To) {
+		return false
+	}
+	if f.ErrorContains != "" && !strings.Contains(strings.ToLower(m.Error), strings.ToLower(f.ErrorContains)) {
+		return false
+	}
+	return true
+}
+
+func newDLQMessage(m kafka.Message) DLQMessage {
+	msg := DLQMessage{
+		Partition:     m.Partition,
+		Offset:        m.Offset,
+		Key:           string(m.Key),
+		Value:         m.Value,
+		Headers:       m.Headers,
+		OriginalTopic: originalTopic(m),
+		Timestamp:     m.Time,
+	}
+
+	msg.Error, _ = headerValue(m.Headers, HeaderError)
+	msg.FailedBy, _ = headerValue(m.Headers, HeaderOrigin)
+	for _, header := range m.Headers {
+		if header.Key == HeaderOrigin {
+			msg.Origin = string(header.Value)
+			break
+		}
+	}
+
+	if value, ok := headerValue(m.Headers, HeaderOriginalPartition); ok {
+		msg.OriginalPartition, _ = strconv.Atoi(value)
+	}
+	if value, ok := headerValue(m.Headers, HeaderOriginalOffset); ok {
+		msg.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+	}
+	if value, ok := headerValue(m.Headers, HeaderReplayCount); ok {
+		msg.ReplayCount, _ = strconv.Atoi(value)
+	}
+
+	return msg
+}
+
+type dlqPosition struct {
+	partition int
+	offset    int64
+}
+
+// dlqCollector collects the messages of the DLQ topic and the replayed markers
+// written by ReplayDLQ.
+type dlqCollector struct {
+	messages []DLQMessage
+	replayed map[dlqPosition]bool
+}
+
+func newDLQCollector() *dlqCollector {
+	return &dlqCollector{replayed: make(map[dlqPosition]bool)}
+}
+
+func (c *dlqCollector) add(m kafka.Message) {
+	value, ok := headerValue(m.Headers, HeaderDLQReplayed)
+	if !ok {
+		c.messages = append(c.messages, newDLQMessage(m))
+		return
+	}
+
+	partition, offset, _ := strings.Cut(value, ":")
+	p, err := strconv.Atoi(partition)
+	if err != nil {
+		return
+	}
+	o, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil {
+		return
+	}
+	c.replayed[dlqPosition{p, o}] = true
+}
+
+// result returns the messages matching filter, the markers may be read after
+// the messages they mark.
+func (c *dlqCollector) result(filter DLQFilter) []DLQMessage {
+	messages := make([]DLQMessage, 0)
+	for _, msg := range c.messages {
+		msg.Replayed = c.replayed[dlqPosition{msg.Partition, msg.Offset}]
+		if !filter.match(msg) {
+			continue
+		}
+		messages = append(messages, msg)
+		if filter.Limit > 0 && len(messages) >= filter.Limit {
+			break
+		}
+	}
+	return messages
+}
+
+// replayedMarker returns the marker written to the DLQ topic once msg was
+// replayed. The key is unique, so a compacted DLQ topic keeps every marker.
+func replayedMarker(topic string, msg DLQMessage) kafka.Message {
+	position := fmt.Sprintf("%d:%d", msg.Partition, msg.Offset)
+	return kafka.Message{
+		Topic:   topic,
+		Key:     []byte(HeaderDLQReplayed + ":" + position),
+		Value:   []byte(position),
+		Headers: []kafka.Header{{Key: HeaderDLQReplayed, Value: []byte(position)}},
+	}
+}
+
+// ListDLQ reads KafkaDlqTopic from the beginning up to the current end of
+// every partition and returns the messages matching the filter. A partition
+// ends early when no message arrived for boundedFetchIdle before its end. It
+// does not use a consumer group, so no offsets are committed. The whole topic
+// is read even with a Limit, the replayed markers of the messages may come
+// after them.
+func (k *Client) ListDLQ(ctx context.Context, filter DLQFilter) ([]DLQMessage, error) {
+	if k.cfg.KafkaDlqTopic == "" {
+		return nil, errors.New("dlq topic not configured")
+	}
+
+	client := k.newAdminClient()
+	partitions, err := k.partitionOffsets(ctx, client, k.cfg.KafkaDlqTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := newDLQCollector()
+	for _, p := range partitions {
+		if p.FirstOffset >= p.LastOffset {
+			continue
+		}
+
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   k.cfg.KafkaBrokers,
+			Topic:     k.cfg.KafkaDlqTopic,
+			Partition: p.Partition,
+			Dialer:    k.newDialer(),
+		})
+		if err := r.SetOffset(p.FirstOffset); err != nil {
+			r.Close()
+			return nil, err
+		}
+
+		// the last offsets may be transaction markers or compacted away, the
+		// bounded fetch then ends the partition with io.EOF
+		bound := &readBound{end: p.LastOffset}
+		for {
+			m, err := bound.fetch(ctx, r)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+
+			deliver, next := bound.check(m)
+			if deliver {
+				collector.add(m)
+			}
+			if !next {
+				break
+			}
+		}
+		common_utils.LogIfError(r.Close())
+	}
+
+	return collector.result(filter), nil
+}
+
+func (k *Client) partitionOffsets(ctx context.Context, client *kafka.Client, topic string) ([]kafka.PartitionOffsets, error) {
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if metadata.Topics[0].Error != nil {
+		return nil, metadata.Topics[0].Error
+	}
+
+	requests := make([]kafka.OffsetRequest, 0, len(metadata.Topics[0].Partitions)*2)
+	for _, p := range metadata.Topics[0].Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+	}
+	return offsets.Topics[topic], nil
+}
+
+// ReplayDLQ publishes the messages back to their original topic. The replay
+// count header is increased on every replay, messages which reached
+// KafkaDlqMaxReplay are skipped to prevent infinite loops. A replayed message
+// is marked in the DLQ topic, so ListDLQ skips it afterwards unless
+// IncludeReplayed is set. It returns the number of replayed messages.
+func (k *Client) ReplayDLQ(ctx context.Context, messages ...DLQMessage) (int, error) {
+	if !k.IsWriters() {
+		return 0, errors.New("writers not created")
+	}
+
+	maxReplay := k.cfg.KafkaDlqMaxReplay
+	if maxReplay <= 0 {
+		maxReplay = defaultDLQMaxReplay
+	}
+
+	var errs []error
+	replayed := 0
+	for _, msg := range messages {
+		if msg.ReplayCount >= maxReplay {
+			errs = append(errs, fmt.Errorf("message %d/%d: %w", msg.Partition, msg.Offset, ErrReplayLimitReached))
+			continue
+		}
+
+		err := k.writeMessages(ctx, kafka.Message{
+			Topic:   msg.OriginalTopic,
+			Key:     []byte(msg.Key),
+			Value:   msg.Value,
+			Headers: replayHeaders(msg),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %d/%d: %w", msg.Partition, msg.Offset, err))
+			continue
+		}
+
+		common_utils.LogInfo(fmt.Sprintf("replayed dlq message %d/%d to %s", msg.Partition, msg.Offset, msg.OriginalTopic))
+		replayed++
+
+		if err := k.writeMessages(ctx, replayedMarker(k.cfg.KafkaDlqTopic, msg)); err != nil {
+			errs = append(errs, fmt.Errorf("message %d/%d replayed but not marked: %w", msg.Partition, msg.Offset, err))
+		}
+	}
+
+	return replayed, errors.Join(errs...)
+}
+
+// replayHeaders drops the failure headers and the origin added by the DLQ, so
+// the replayed message looks like the original one plus the replay count.
+func replayHeaders(msg DLQMessage) []kafka.Header {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+1)
+	hasOrigin := false
+	for _, header := range msg.Headers {
+		switch header.Key {
+		case HeaderError, HeaderRetryAttempt, HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderReplayCount:
+			continue
+		case HeaderOrigin:
+			if hasOrigin {
+				continue
+			}
+			hasOrigin = true
+		}
+		headers = append(headers, header)
+	}
+
+	return append(headers, kafka.Header{
+		Key:   HeaderReplayCount,
+		Value: []byte(strconv.Itoa(msg.ReplayCount + 1)),
+	})
+}
@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDLQMessage(t *testing.T) {
+	now := time.Now()
+	m := kafka.Message{
+		Topic:     "dlq",
+		Partition: 1,
+		Offset:    10,
+		Key:       []byte("key"),
+		Time:      now,
+		Headers: []kafka.Header{
+			{Key: HeaderOrigin, Value: []byte("producer-svc")},
+			{Key: HeaderOriginalTopic, Value: []byte("Users.Signup.v1")},
+			{Key: HeaderOriginalPartition, Value: []byte("3")},
+			{Key: HeaderOriginalOffset, Value: []byte("99")},
+			{Key: HeaderRetryAttempt, Value: []byte("3")},
+			{Key: HeaderError, Value: []byte("Connection Refused")},
+			{Key: HeaderOrigin, Value: []byte("consumer-svc")},
+		},
+	}
+
+	msg := newDLQMessage(m)
+
+	t.Run("Parse DLQ headers", func(t *testing.T) {
+		assert.Equal(t, "Users.Signup.v1", msg.OriginalTopic)
+		assert.Equal(t, 3, msg.OriginalPartition)
+		assert.Equal(t, int64(99), msg.OriginalOffset)
+		assert.Equal(t, "Connection Refused", msg.Error)
+		assert.Equal(t, "producer-svc", msg.Origin)
+		assert.Equal(t, "consumer-svc", msg.FailedBy)
+		assert.Equal(t, 0, msg.ReplayCount)
+	})
+
+	t.Run("Filter DLQ messages", func(t *testing.T) {
+		assert.True(t, DLQFilter{}.match(msg))
+		assert.True(t, DLQFilter{Topic: "Users.Signup.v1", ErrorContains: "refused"}.match(msg))
+		assert.False(t, DLQFilter{Topic: "Users.Change_Password.v1"}.match(msg))
+		assert.False(t, DLQFilter{From: now.Add(time.Minute)}.match(msg))
+		assert.False(t, DLQFilter{To: now.Add(-time.Minute)}.match(msg))
+	})
+
+	t.Run("Skip replayed DLQ messages", func(t *testing.T) {
+		collector := newDLQCollector()
+		collector.add(m)
+		collector.add(replayedMarker("dlq", msg))
+
+		assert.Empty(t, collector.result(DLQFilter{}))
+		messages := collector.result(DLQFilter{IncludeReplayed: true})
+		if assert.Len(t, messages, 1) {
+			assert.True(t, messages[0].Replayed)
+		}
+	})
+
+	t.Run("Build replay headers", func(t *testing.T) {
+		headers := replayHeaders(msg)
+
+		assert.Len(t, headers, 2)
+		origin, _ := headerValue(headers, HeaderOrigin)
+		assert.Equal(t, "producer-svc", origin)
+		count, _ := headerValue(headers, HeaderReplayCount)
+		assert.Equal(t, "1", count)
+
+		replayed := newDLQMessage(kafka.Message{Headers: headers})
+		assert.Equal(t, 1, replayed.ReplayCount)
+	})
+}
@@ -24,15 +24,21 @@ type Event struct {
 }
 
 // NewEvent creates a new event, with the given aggregateID, eventType and data.
-// The eventID is generated automatically, and the version is set to 0.
+// The eventID is generated automatically, and the version is set to the latest
+// version of the upcasters registered with RegisterUpcaster (0 without).
 func NewEvent(eventType EventType, data []byte, metadata ...[]byte) *Event {
+	return NewVersionedEvent(eventType, defaultUpcasters.LatestVersion(eventType), data, metadata...)
+}
+
+// NewVersionedEvent creates a new event like NewEvent with an explicit version.
+func NewVersionedEvent(eventType EventType, version uint64, data []byte, metadata ...[]byte) *Event {
 	if len(metadata) == 0 {
 		metadata = append(metadata, []byte("{}"))
 	}
 	return &Event{
 		EventID:   uuid.New().String(),
 		EventType: eventType,
-		Version:   0,
+		Version:   version,
 		Data:      data,
 		Metadata:  metadata[0],
 		Timestamp: time.Now(),
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	HeaderOrigin            = "origin"
+	HeaderError             = "error"
+	HeaderRetryAttempt      = "retry-attempt"
+	HeaderOriginalTopic     = "original-topic"
+	HeaderOriginalPartition = "original-partition"
+	HeaderOriginalOffset    = "original-offset"
+	HeaderReplayCount       = "replay-count"
+	// HeaderDLQReplayed marks the DLQ message written once the DLQ message at
+	// the partition:offset of its value was replayed.
+	HeaderDLQReplayed = "dlq-replayed"
+)
+
+// headerValue returns the last value of the header key.
+func headerValue(headers []kafka.Header, key string) (string, bool) {
+	for i := len(headers) - 1; i >= 0; i-- {
+		if headers[i].Key == key {
+			return string(headers[i].Value), true
+		}
+	}
+	return "", false
+}
+
+// setHeader replaces every value of the header key with value.
+func setHeader(headers []kafka.Header, key string, value string) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers)+1)
+	for _, header := range headers {
+		if header.Key != key {
+			result = append(result, header)
+		}
+	}
+	return append(result, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// withOriginalHeaders records where the message was consumed first, messages
+// coming from a retry topic already carry these headers.
+func withOriginalHeaders(m kafka.Message) []kafka.Header {
+	if _, ok := headerValue(m.Headers, HeaderOriginalTopic); ok {
+		return m.Headers
+	}
+
+	headers := setHeader(m.Headers, HeaderOriginalTopic, m.Topic)
+	headers = setHeader(headers, HeaderOriginalPartition, strconv.Itoa(m.Partition))
+	return setHeader(headers, HeaderOriginalOffset, strconv.FormatInt(m.Offset, 10))
+}
+
+func originalTopic(m kafka.Message) string {
+	if topic, ok := headerValue(m.Headers, HeaderOriginalTopic); ok && topic != "" {
+		return topic
+	}
+	return m.Topic
+}
+
+func retryAttempt(m kafka.Message) int {
+	value, ok := headerValue(m.Headers, HeaderRetryAttempt)
+	if !ok {
+		return 1
+	}
+
+	attempt, err := strconv.Atoi(value)
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
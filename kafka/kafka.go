@@ -2,6 +2,10 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -9,22 +13,71 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+const defaultDrainTimeout = 30 * time.Second
+
+// ErrDrainTimeout is returned by Shutdown when in-flight messages did not
+// finish before the drain timeout.
+var ErrDrainTimeout = errors.New("kafka drain timeout")
+
 type HandlerFunc func(context.Context, *Message) error
 
+// PermanentError marks a handler error which must not be retried, the message
+// is moved to the DLQ right away.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err into a PermanentError.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
 type IClient interface {
 	Listen(f HandlerFunc) error
 	ListenTopic(topic string, f HandlerFunc) error
+	ListenWithContext(ctx context.Context, f HandlerFunc) error
+	ListenTopicWithContext(ctx context.Context, topic string, f HandlerFunc) error
 	NewConsumer()
+	ConfigureTopic(topic string, config ConsumerConfig)
 	IsWriters() bool
 	Close() error
+	Shutdown(ctx context.Context) error
 
 	NewPublisher() error
 	Publish(ctx context.Context, topic string, msg Event) error
 	PublishWithTracer(ctx context.Context, topic string, msg Event) error
-	publishToDLQ(ctx context.Context, m kafka.Message) error
+	PublishWithOptions(ctx context.Context, topic string, msg Event, opts PublishOptions) error
+	PublishBatch(ctx context.Context, topic string, msgs []Event, opts ...PublishOptions) error
+	PublishAsync(ctx context.Context, topic string, msg Event, callback PublishCallback, opts ...PublishOptions) error
+	ClosePublisher() error
 	IsReaderConnected() bool
 
+	ListDLQ(ctx context.Context, filter DLQFilter) ([]DLQMessage, error)
+	ReplayDLQ(ctx context.Context, messages ...DLQMessage) (int, error)
+
 	CreateTopic(topic string, numPart int) error
+	CreateTopicIfNotExists(ctx context.Context, specs ...TopicSpec) error
+	DeleteTopics(ctx context.Context, topics ...string) error
+	AddPartitions(ctx context.Context, topic string, count int) error
+	DescribeTopic(ctx context.Context, topic string, configNames ...string) (*TopicDescription, error)
+	ListConsumerGroups(ctx context.Context) ([]string, error)
+	ResetOffsetsToEarliest(ctx context.Context, groupID, topic string) error
+	ResetOffsetsToLatest(ctx context.Context, groupID, topic string) error
+	ResetOffsetsToTime(ctx context.Context, groupID, topic string, at time.Time) error
+
+	SetSchemaRegistry(registry SchemaRegistry)
+	RegisterSchema(ctx context.Context, topic string, schema string) (int, error)
 }
 
 // Message define message encode/decode sarama message
@@ -37,26 +90,74 @@ type Message struct {
 	Timestamp     int64  `json:"timestamp,omitempty"`
 	ConsumerGroup string `json:"consumer_group,omitempty"`
 	Retry         int    `json:"retry,omitempty"`
+	SchemaID      int    `json:"schema_id,omitempty"`
 	Commit        func() error
 	MoveToDLQ     func() error
 	Headers       map[string]string
 }
 
 type Client struct {
-	writer *kafka.Writer
+	// writer is the default writer, read by the publishers while
+	// ClosePublisher may reset it.
+	writer    atomic.Pointer[kafka.Writer]
+	writersMu sync.Mutex
+	writers   map[writerConfig]*kafka.Writer
 
-	readers map[string]*kafka.Reader
+	readers map[string]messageReader
 	cfg     *common_utils.BaseConfig
+	// Deprecated: failed messages are republished to retry topics, see
+	// KafkaRetryDelays.
 	Backoff backoff.BackOff
+
+	retryTiers  []retryTier
+	retryDelays map[string]time.Duration
+	schemas     schemaCache
+	metrics     *clientMetrics
+	auth        connAuth
+
+	// consumerConfigs are set with ConfigureTopic, guarded by mu.
+	consumerConfigs map[string]ConsumerConfig
+
+	mu       sync.Mutex
+	cancels  []context.CancelFunc
+	fetchers sync.WaitGroup
+	inflight sync.WaitGroup
 }
 
+// NewKafkaClient creates a client of KAFKA_BROKERS. An invalid SASL or TLS
+// config is logged and the connections are not authenticated, use
+// NewKafkaClientWithAuth to handle the error.
 func NewKafkaClient(cfg *common_utils.BaseConfig) IClient {
+	auth, err := newConnAuth(cfg)
+	if err != nil {
+		common_utils.LogError(fmt.Sprintf("invalid kafka sasl/tls config, connecting without: %v", err))
+	}
+
+	return newKafkaClient(cfg, auth)
+}
+
+// NewKafkaClientWithAuth creates a client like NewKafkaClient, but returns the
+// error of an invalid SASL or TLS config.
+func NewKafkaClientWithAuth(cfg *common_utils.BaseConfig) (IClient, error) {
+	auth, err := newConnAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKafkaClient(cfg, auth), nil
+}
+
+func newKafkaClient(cfg *common_utils.BaseConfig, auth connAuth) *Client {
 	backoff := backoff.NewExponentialBackOff()
 	backoff.MaxElapsedTime = time.Minute * 5
 
 	return &Client{
-		cfg:     cfg,
-		readers: make(map[string]*kafka.Reader),
-		Backoff: backoff,
+		auth:        auth,
+		cfg:         cfg,
+		readers:     make(map[string]messageReader),
+		Backoff:     backoff,
+		retryTiers:  parseRetryTiers(cfg.KafkaRetryDelays),
+		retryDelays: make(map[string]time.Duration),
+		metrics:     newClientMetrics(cfg.KafkaGroupID),
 	}
 }
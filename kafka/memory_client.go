@@ -0,0 +1,615 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+// MemoryClient is an in-memory IClient for tests. Every topic is a single
+// partition log kept in memory. Published messages are only handed to the
+// handlers on Flush, in publish order, so tests stay deterministic:
+//
+//	client := kafka.NewMemoryClient(cfg)
+//	client.NewConsumer()
+//	client.ListenTopic("Users.Signup.v1", handler)
+//
+//	svc.Signup(ctx, user) // publishes with client
+//	err := client.Flush(ctx)
+//
+// Failed messages follow the rules of Client: they are republished to the
+// retry topics (without delay) up to KafkaDlqRetry attempts and then moved to
+// KafkaDlqTopic, permanent errors go to the DLQ right away.
+type MemoryClient struct {
+	cfg        *common_utils.BaseConfig
+	retryTiers []retryTier
+	schemas    schemaCache
+
+	mu        sync.Mutex
+	writer    bool
+	consumer  bool
+	seq       int64
+	topics    map[string][]memoryRecord
+	next      map[string]int
+	committed map[string]map[int64]bool
+	handlers  map[string]memoryHandler
+}
+
+var _ IClient = (*MemoryClient)(nil)
+
+type memoryRecord struct {
+	seq int64
+	msg kafka.Message
+}
+
+type memoryHandler struct {
+	ctx context.Context
+	f   HandlerFunc
+}
+
+func NewMemoryClient(cfg *common_utils.BaseConfig) *MemoryClient {
+	return &MemoryClient{
+		cfg:        cfg,
+		retryTiers: parseRetryTiers(cfg.KafkaRetryDelays),
+		topics:     make(map[string][]memoryRecord),
+		next:       make(map[string]int),
+		committed:  make(map[string]map[int64]bool),
+		handlers:   make(map[string]memoryHandler),
+	}
+}
+
+// ConfigureTopic is a no-op, every topic of the in-memory log has a single
+// partition and a single consumer.
+func (c *MemoryClient) ConfigureTopic(topic string, config ConsumerConfig) {}
+
+func (c *MemoryClient) NewConsumer() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumer = true
+}
+
+func (c *MemoryClient) IsReaderConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.consumer
+}
+
+func (c *MemoryClient) NewPublisher() error {
+	if len(c.cfg.KafkaBrokers) == 0 {
+		return errors.New("not found broker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writer = true
+	return nil
+}
+
+func (c *MemoryClient) IsWriters() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writer
+}
+
+func (c *MemoryClient) ClosePublisher() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writer = false
+	return nil
+}
+
+func (c *MemoryClient) Close() error {
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown removes every handler, messages published afterwards stay pending.
+func (c *MemoryClient) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = make(map[string]memoryHandler)
+	return nil
+}
+
+func (c *MemoryClient) Listen(f HandlerFunc) error {
+	return c.ListenWithContext(context.Background(), f)
+}
+
+func (c *MemoryClient) ListenWithContext(ctx context.Context, f HandlerFunc) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.consumer {
+		return nil
+	}
+	for _, topic := range c.cfg.KafkaTopics {
+		c.handlers[topic] = memoryHandler{ctx: ctx, f: f}
+	}
+	return nil
+}
+
+func (c *MemoryClient) ListenTopic(topic string, f HandlerFunc) error {
+	return c.ListenTopicWithContext(context.Background(), topic, f)
+}
+
+func (c *MemoryClient) ListenTopicWithContext(ctx context.Context, topic string, f HandlerFunc) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.consumer || !slices.Contains(c.cfg.KafkaTopics, topic) {
+		return errors.New("listen topic not found")
+	}
+	c.handlers[topic] = memoryHandler{ctx: ctx, f: f}
+	return nil
+}
+
+func (c *MemoryClient) CreateTopic(topic string, numPart int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.topics[topic]; !ok {
+		c.topics[topic] = nil
+	}
+	return nil
+}
+
+func (c *MemoryClient) CreateTopicIfNotExists(ctx context.Context, specs ...TopicSpec) error {
+	for _, spec := range specs {
+		common_utils.LogIfError(c.CreateTopic(spec.Topic, spec.NumPartitions))
+	}
+	return nil
+}
+
+// DeleteTopics drops the messages and commits of the topics, unknown topics
+// are reported as error.
+func (c *MemoryClient) DeleteTopics(ctx context.Context, topics ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for _, topic := range topics {
+		if _, ok := c.topics[topic]; !ok {
+			errs = append(errs, fmt.Errorf("delete topic %s: not found", topic))
+			continue
+		}
+		delete(c.topics, topic)
+		delete(c.next, topic)
+		delete(c.committed, topic)
+	}
+	return errors.Join(errs...)
+}
+
+// AddPartitions only checks that topic exists, the topics of the in-memory
+// log keep a single partition.
+func (c *MemoryClient) AddPartitions(ctx context.Context, topic string, count int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.topics[topic]; !ok {
+		return fmt.Errorf("topic %s not found", topic)
+	}
+	return nil
+}
+
+// DescribeTopic returns the single partition of topic without configs.
+func (c *MemoryClient) DescribeTopic(ctx context.Context, topic string, configNames ...string) (*TopicDescription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.topics[topic]; !ok {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	return &TopicDescription{
+		Topic:      topic,
+		Partitions: []kafka.Partition{{Topic: topic, ID: 0}},
+		Configs:    make(map[string]string),
+	}, nil
+}
+
+// ListConsumerGroups returns KafkaGroupID once the consumer is created.
+func (c *MemoryClient) ListConsumerGroups(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.consumer || c.cfg.KafkaGroupID == "" {
+		return []string{}, nil
+	}
+	return []string{c.cfg.KafkaGroupID}, nil
+}
+
+// ResetOffsetsToEarliest hands the messages of topic to the handlers again on
+// the next Flush. The in-memory log has a single consumer, groupID is
+// ignored by the resets.
+func (c *MemoryClient) ResetOffsetsToEarliest(ctx context.Context, groupID, topic string) error {
+	return c.resetOffset(topic, func(records []memoryRecord) int { return 0 })
+}
+
+// ResetOffsetsToLatest skips the pending messages of topic.
+func (c *MemoryClient) ResetOffsetsToLatest(ctx context.Context, groupID, topic string) error {
+	return c.resetOffset(topic, func(records []memoryRecord) int { return len(records) })
+}
+
+// ResetOffsetsToTime moves topic to the first message written at or after at.
+func (c *MemoryClient) ResetOffsetsToTime(ctx context.Context, groupID, topic string, at time.Time) error {
+	return c.resetOffset(topic, func(records []memoryRecord) int {
+		for i, record := range records {
+			if !record.msg.Time.Before(at) {
+				return i
+			}
+		}
+		return len(records)
+	})
+}
+
+func (c *MemoryClient) resetOffset(topic string, offset func(records []memoryRecord) int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records, ok := c.topics[topic]
+	if !ok {
+		return fmt.Errorf("topic %s not found", topic)
+	}
+	c.next[topic] = offset(records)
+	return nil
+}
+
+// SetSchemaRegistry sets the registry used by RegisterSchema, the messages of
+// the in-memory log stay JSON without the wire format.
+func (c *MemoryClient) SetSchemaRegistry(registry SchemaRegistry) {
+	c.schemas.set(registry)
+}
+
+// RegisterSchema checks schema against the latest version of the subject of
+// topic and registers it.
+func (c *MemoryClient) RegisterSchema(ctx context.Context, topic string, schema string) (int, error) {
+	return c.schemas.register(ctx, topic, schema)
+}
+
+func (c *MemoryClient) Publish(ctx context.Context, topic string, event Event) error {
+	return c.PublishWithOptions(ctx, topic, event, PublishOptions{})
+}
+
+func (c *MemoryClient) PublishWithTracer(ctx context.Context, topic string, event Event) error {
+	return c.PublishWithOptions(ctx, topic, event, PublishOptions{})
+}
+
+func (c *MemoryClient) PublishWithOptions(ctx context.Context, topic string, event Event, opts PublishOptions) error {
+	msg, err := c.newMessage(ctx, topic, event, opts)
+	if err != nil {
+		return err
+	}
+	return c.write(msg)
+}
+
+func (c *MemoryClient) PublishBatch(ctx context.Context, topic string, events []Event, opts ...PublishOptions) error {
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		msg, err := c.newMessage(ctx, topic, event, firstOptions(opts))
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	return c.write(msgs...)
+}
+
+// PublishAsync publishes right away and calls callback before returning.
+func (c *MemoryClient) PublishAsync(ctx context.Context, topic string, event Event, callback PublishCallback, opts ...PublishOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := c.PublishWithOptions(ctx, topic, event, firstOptions(opts))
+	if callback != nil {
+		callback(event, err)
+	}
+	return err
+}
+
+func (c *MemoryClient) newMessage(ctx context.Context, topic string, event Event, opts PublishOptions) (kafka.Message, error) {
+	if topic == "" {
+		return kafka.Message{}, errors.New("topic not empty")
+	}
+
+	eventPayload, err := common_utils.Marshal(event)
+	if err != nil {
+		return kafka.Message{}, errors.New("message of data sender can not marshal")
+	}
+
+	key := opts.Key
+	if len(key) == 0 {
+		key = []byte(hashMessage(eventPayload))
+	}
+
+	headers := tracer.GetKafkaTracingHeadersFromSpanCtx(ctx)
+	for name, value := range opts.Headers {
+		headers = append(headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+	headers = append(headers, kafka.Header{
+		Key:   HeaderOrigin,
+		Value: []byte(c.cfg.ServiceName),
+	})
+
+	return kafka.Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   eventPayload,
+		Headers: headers,
+	}, nil
+}
+
+func (c *MemoryClient) write(msgs ...kafka.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.writer {
+		return errors.New("writers not created")
+	}
+
+	for _, m := range msgs {
+		if m.Topic == "" {
+			return errors.New("topic not empty")
+		}
+
+		c.seq++
+		m.Partition = 0
+		m.Offset = int64(len(c.topics[m.Topic]))
+		m.Time = time.Now()
+		c.topics[m.Topic] = append(c.topics[m.Topic], memoryRecord{seq: c.seq, msg: m})
+	}
+	return nil
+}
+
+// Messages returns the messages written to topic, including the retry topics
+// and the DLQ topic.
+func (c *MemoryClient) Messages(topic string) []kafka.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msgs := make([]kafka.Message, 0, len(c.topics[topic]))
+	for _, record := range c.topics[topic] {
+		msgs = append(msgs, record.msg)
+	}
+	return msgs
+}
+
+// Events returns the events published to topic.
+func (c *MemoryClient) Events(topic string) ([]Event, error) {
+	msgs := c.Messages(topic)
+
+	events := make([]Event, 0, len(msgs))
+	for _, m := range msgs {
+		var event Event
+		if err := common_utils.Unmarshal(m.Value, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// IsCommitted reports whether the message at offset of topic was committed.
+func (c *MemoryClient) IsCommitted(topic string, offset int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.committed[topic][offset]
+}
+
+// Reset drops every message and commit, the handlers are kept.
+func (c *MemoryClient) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.topics = make(map[string][]memoryRecord)
+	c.next = make(map[string]int)
+	c.committed = make(map[string]map[int64]bool)
+}
+
+// Flush hands the pending messages to the handlers in publish order until no
+// message is left, including the messages published by the handlers and the
+// retries. Messages of topics without handler stay pending.
+func (c *MemoryClient) Flush(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m, handler, ok := c.nextMessage()
+		if !ok {
+			return nil
+		}
+		c.handleMessage(handler.ctx, m, handler.f)
+	}
+}
+
+func (c *MemoryClient) nextMessage() (kafka.Message, memoryHandler, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		next    *memoryRecord
+		handler memoryHandler
+	)
+	for topic, records := range c.topics {
+		i := c.next[topic]
+		if i >= len(records) {
+			continue
+		}
+
+		h, ok := c.handlerFor(topic, records[i].msg)
+		if !ok || h.ctx.Err() != nil {
+			continue
+		}
+		if next == nil || records[i].seq < next.seq {
+			next = &records[i]
+			handler = h
+		}
+	}
+
+	if next == nil {
+		return kafka.Message{}, memoryHandler{}, false
+	}
+	c.next[next.msg.Topic]++
+	return next.msg, handler, true
+}
+
+// handlerFor returns the handler of the messages of topic, the messages of
+// the retry topics are handled by the handler of their original topic.
+func (c *MemoryClient) handlerFor(topic string, m kafka.Message) (memoryHandler, bool) {
+	if topic == c.cfg.KafkaDlqTopic {
+		return memoryHandler{}, false
+	}
+
+	original := originalTopic(m)
+	if original != topic {
+		isRetryTopic := false
+		for _, tier := range c.retryTiers {
+			if RetryTopic(original, tier.name) == topic {
+				isRetryTopic = true
+			}
+		}
+		if !isRetryTopic {
+			return memoryHandler{}, false
+		}
+	}
+
+	handler, ok := c.handlers[original]
+	return handler, ok
+}
+
+func (c *MemoryClient) handleMessage(ctx context.Context, m kafka.Message, f HandlerFunc) {
+	attempt := retryAttempt(m)
+
+	msg := &Message{
+		Offset:    m.Offset,
+		Partition: m.Partition,
+		Topic:     originalTopic(m),
+		Headers:   tracer.TextMapCarrierFromKafkaMessageHeaders(m.Headers),
+		Body:      m.Value,
+		Timestamp: m.Time.Unix(),
+		Key:       string(m.Key),
+		Retry:     attempt,
+		Commit: func() error {
+			return c.commit(m)
+		},
+		MoveToDLQ: func() error {
+			return c.publishToDLQ(m)
+		},
+	}
+
+	err := f(ctx, msg)
+	if err == nil {
+		if c.cfg.KafkaAutoCommit {
+			common_utils.LogIfError(c.commit(m))
+		}
+		return
+	}
+
+	var permanent *PermanentError
+	if attempt < c.cfg.KafkaDlqRetry && !errors.As(err, &permanent) {
+		if retryErr := c.publishToRetry(m, attempt+1, err); retryErr == nil {
+			common_utils.LogIfError(c.commit(m))
+			return
+		}
+	}
+
+	m.Headers = setHeader(m.Headers, HeaderError, err.Error())
+	common_utils.LogIfError(c.publishToDLQ(m))
+	common_utils.LogIfError(c.commit(m))
+}
+
+func (c *MemoryClient) commit(m kafka.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.committed[m.Topic] == nil {
+		c.committed[m.Topic] = make(map[int64]bool)
+	}
+	c.committed[m.Topic][m.Offset] = true
+	return nil
+}
+
+func (c *MemoryClient) publishToRetry(m kafka.Message, attempt int, cause error) error {
+	tier, ok := tierFor(c.retryTiers, attempt)
+	if !ok {
+		return errors.New("retry topics not configured")
+	}
+
+	headers := withOriginalHeaders(m)
+	headers = setHeader(headers, HeaderRetryAttempt, strconv.Itoa(attempt))
+	headers = setHeader(headers, HeaderError, cause.Error())
+
+	return c.write(kafka.Message{
+		Topic:   RetryTopic(originalTopic(m), tier.name),
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	})
+}
+
+func (c *MemoryClient) publishToDLQ(m kafka.Message) error {
+	if c.cfg.KafkaDlqTopic == "" {
+		return errors.New("dlq topic not configured")
+	}
+
+	m.Headers = withOriginalHeaders(m)
+	m.Topic = c.cfg.KafkaDlqTopic
+	m.Headers = append(m.Headers, kafka.Header{
+		Key:   HeaderOrigin,
+		Value: []byte(c.cfg.ServiceName),
+	})
+	return c.write(m)
+}
+
+func (c *MemoryClient) ListDLQ(ctx context.Context, filter DLQFilter) ([]DLQMessage, error) {
+	if c.cfg.KafkaDlqTopic == "" {
+		return nil, errors.New("dlq topic not configured")
+	}
+
+	collector := newDLQCollector()
+	for _, m := range c.Messages(c.cfg.KafkaDlqTopic) {
+		collector.add(m)
+	}
+	return collector.result(filter), nil
+}
+
+func (c *MemoryClient) ReplayDLQ(ctx context.Context, messages ...DLQMessage) (int, error) {
+	maxReplay := c.cfg.KafkaDlqMaxReplay
+	if maxReplay <= 0 {
+		maxReplay = defaultDLQMaxReplay
+	}
+
+	var errs []error
+	replayed := 0
+	for _, msg := range messages {
+		if msg.ReplayCount >= maxReplay {
+			errs = append(errs, fmt.Errorf("message %d/%d: %w", msg.Partition, msg.Offset, ErrReplayLimitReached))
+			continue
+		}
+
+		err := c.write(kafka.Message{
+			Topic:   msg.OriginalTopic,
+			Key:     []byte(msg.Key),
+			Value:   msg.Value,
+			Headers: replayHeaders(msg),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %d/%d: %w", msg.Partition, msg.Offset, err))
+			continue
+		}
+		replayed++
+
+		if err := c.write(replayedMarker(c.cfg.KafkaDlqTopic, msg)); err != nil {
+			errs = append(errs, fmt.Errorf("message %d/%d replayed but not marked: %w", msg.Partition, msg.Offset, err))
+		}
+	}
+
+	return replayed, errors.Join(errs...)
+}
@@ -0,0 +1,157 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMemoryClient(t *testing.T) *MemoryClient {
+	client := NewMemoryClient(&common_utils.BaseConfig{
+		ServiceName:      "tester",
+		KafkaBrokers:     []string{"localhost:9092"},
+		KafkaTopics:      []string{"Users.Signup.v1", "Users.Welcome.v1"},
+		KafkaDlqTopic:    "dlq",
+		KafkaDlqRetry:    3,
+		KafkaRetryDelays: []string{"5s", "1m"},
+	})
+	client.NewConsumer()
+	assert.NoError(t, client.NewPublisher())
+	return client
+}
+
+func TestMemoryClientPublishAndListen(t *testing.T) {
+	client := newTestMemoryClient(t)
+	ctx := context.Background()
+
+	var handled []string
+	assert.NoError(t, client.ListenTopic("Users.Signup.v1", func(ctx context.Context, msg *Message) error {
+		handled = append(handled, msg.Topic)
+		assert.NoError(t, client.Publish(ctx, "Users.Welcome.v1", *NewEvent("welcome", []byte("{}"))))
+		return msg.Commit()
+	}))
+	assert.NoError(t, client.ListenTopic("Users.Welcome.v1", func(ctx context.Context, msg *Message) error {
+		handled = append(handled, msg.Topic)
+		return nil
+	}))
+	assert.Error(t, client.ListenTopic("unknown", nil))
+
+	event := NewEvent("signup", []byte(`{"name":"tester"}`))
+	assert.NoError(t, client.PublishWithOptions(ctx, "Users.Signup.v1", *event, PublishOptions{Key: []byte("user-1")}))
+	assert.NoError(t, client.Flush(ctx))
+
+	assert.Equal(t, []string{"Users.Signup.v1", "Users.Welcome.v1"}, handled)
+	assert.True(t, client.IsCommitted("Users.Signup.v1", 0))
+	assert.False(t, client.IsCommitted("Users.Welcome.v1", 0))
+
+	events, err := client.Events("Users.Signup.v1")
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, event.EventID, events[0].EventID)
+	assert.Equal(t, event.Data, events[0].Data)
+	assert.Equal(t, "user-1", string(client.Messages("Users.Signup.v1")[0].Key))
+}
+
+func TestMemoryClientRetryAndDLQ(t *testing.T) {
+	client := newTestMemoryClient(t)
+	ctx := context.Background()
+
+	var attempts []int
+	assert.NoError(t, client.ListenTopic("Users.Signup.v1", func(ctx context.Context, msg *Message) error {
+		attempts = append(attempts, msg.Retry)
+		return errors.New("failed")
+	}))
+
+	assert.NoError(t, client.Publish(ctx, "Users.Signup.v1", *NewEvent("signup", []byte("{}"))))
+	assert.NoError(t, client.Flush(ctx))
+
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+	assert.Len(t, client.Messages(RetryTopic("Users.Signup.v1", "5s")), 1)
+	assert.Len(t, client.Messages(RetryTopic("Users.Signup.v1", "1m")), 1)
+	assert.True(t, client.IsCommitted("Users.Signup.v1", 0))
+
+	messages, err := client.ListDLQ(ctx, DLQFilter{Topic: "Users.Signup.v1"})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "failed", messages[0].Error)
+	assert.Equal(t, int64(0), messages[0].OriginalOffset)
+
+	attempts = nil
+	replayed, err := client.ReplayDLQ(ctx, messages...)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.NoError(t, client.Flush(ctx))
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+
+	// the replayed message is skipped, the one of the failed replay is listed
+	messages, err = client.ListDLQ(ctx, DLQFilter{Topic: "Users.Signup.v1"})
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, 1, messages[0].ReplayCount)
+	}
+
+	messages, err = client.ListDLQ(ctx, DLQFilter{Topic: "Users.Signup.v1", IncludeReplayed: true})
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 2) {
+		assert.True(t, messages[0].Replayed)
+		assert.False(t, messages[1].Replayed)
+	}
+}
+
+func TestMemoryClientPermanentError(t *testing.T) {
+	client := newTestMemoryClient(t)
+	ctx := context.Background()
+
+	calls := 0
+	assert.NoError(t, client.Listen(func(ctx context.Context, msg *Message) error {
+		calls++
+		return Permanent(errors.New("invalid"))
+	}))
+
+	assert.NoError(t, client.Publish(ctx, "Users.Signup.v1", *NewEvent("signup", []byte("{}"))))
+	assert.NoError(t, client.Flush(ctx))
+
+	assert.Equal(t, 1, calls)
+	assert.Len(t, client.Messages("dlq"), 1)
+}
+
+func TestMemoryClientAdmin(t *testing.T) {
+	client := newTestMemoryClient(t)
+	ctx := context.Background()
+
+	var handled int
+	assert.NoError(t, client.ListenTopic("Users.Signup.v1", func(ctx context.Context, msg *Message) error {
+		handled++
+		return msg.Commit()
+	}))
+	assert.NoError(t, client.Publish(ctx, "Users.Signup.v1", *NewEvent("signup", []byte("{}"))))
+	assert.NoError(t, client.Flush(ctx))
+	assert.Equal(t, 1, handled)
+
+	assert.NoError(t, client.ResetOffsetsToEarliest(ctx, "tester", "Users.Signup.v1"))
+	assert.NoError(t, client.Flush(ctx))
+	assert.Equal(t, 2, handled)
+
+	assert.NoError(t, client.Publish(ctx, "Users.Signup.v1", *NewEvent("signup", []byte("{}"))))
+	assert.NoError(t, client.ResetOffsetsToLatest(ctx, "tester", "Users.Signup.v1"))
+	assert.NoError(t, client.Flush(ctx))
+	assert.Equal(t, 2, handled)
+
+	description, err := client.DescribeTopic(ctx, "Users.Signup.v1")
+	assert.NoError(t, err)
+	assert.Len(t, description.Partitions, 1)
+
+	assert.NoError(t, client.DeleteTopics(ctx, "Users.Signup.v1"))
+	assert.Empty(t, client.Messages("Users.Signup.v1"))
+	assert.Error(t, client.DeleteTopics(ctx, "Users.Signup.v1"))
+	assert.Error(t, client.ResetOffsetsToEarliest(ctx, "tester", "Users.Signup.v1"))
+
+	_, err = client.RegisterSchema(ctx, "Users.Signup.v1", `{"type":"string"}`)
+	assert.Error(t, err)
+	client.SetSchemaRegistry(NewInMemorySchemaRegistry())
+	_, err = client.RegisterSchema(ctx, "Users.Signup.v1", `{"type":"string"}`)
+	assert.NoError(t, err)
+}
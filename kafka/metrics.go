@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/dispenal/go-common/kafka"
+
+// clientMetrics are exported through the global meter provider, which is set
+// up by tracer.NewTracer.
+type clientMetrics struct {
+	group string
+
+	fetched   metric.Int64Counter
+	processed metric.Int64Counter
+	failed    metric.Int64Counter
+	retried   metric.Int64Counter
+	dlq       metric.Int64Counter
+	latency   metric.Float64Histogram
+
+	writerBatch  metric.Int64Histogram
+	writerErrors metric.Int64Counter
+
+	mu  sync.Mutex
+	lag map[topicPartition]int64
+}
+
+func newClientMetrics(group string) *clientMetrics {
+	meter := otel.GetMeterProvider().Meter(meterName)
+	m := &clientMetrics{
+		group: group,
+		lag:   make(map[topicPartition]int64),
+	}
+
+	var err error
+	counters := []struct {
+		instrument  *metric.Int64Counter
+		name        string
+		description string
+	}{
+		{&m.fetched, "kafka.consumer.messages.fetched", "The number of fetched messages"},
+		{&m.processed, "kafka.consumer.messages.processed", "The number of successfully handled messages"},
+		{&m.failed, "kafka.consumer.messages.failed", "The number of failed handler calls"},
+		{&m.retried, "kafka.consumer.messages.retried", "The number of messages published to a retry topic"},
+		{&m.dlq, "kafka.consumer.messages.dlq", "The number of messages moved to the DLQ"},
+		{&m.writerErrors, "kafka.writer.errors", "The number of failed writes"},
+	}
+	for _, c := range counters {
+		*c.instrument, err = meter.Int64Counter(c.name, metric.WithDescription(c.description))
+		handleMetricErr(err)
+	}
+
+	m.latency, err = meter.Float64Histogram(
+		"kafka.consumer.handler.latency",
+		metric.WithDescription("The latency of the message handlers"),
+		metric.WithUnit("ms"),
+	)
+	handleMetricErr(err)
+
+	m.writerBatch, err = meter.Int64Histogram(
+		"kafka.writer.batch.size",
+		metric.WithDescription("The number of messages per write"),
+	)
+	handleMetricErr(err)
+
+	_, err = meter.Int64ObservableGauge(
+		"kafka.consumer.lag",
+		metric.WithDescription("The number of messages behind the partition high watermark"),
+		metric.WithInt64Callback(m.observeLag),
+	)
+	handleMetricErr(err)
+
+	return m
+}
+
+func (m *clientMetrics) attributes(topic string, partition int) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String("topic", topic),
+		attribute.String("group", m.group),
+		attribute.Int("partition", partition),
+	)
+}
+
+func (m *clientMetrics) fetch(ctx context.Context, msg kafka.Message) {
+	m.fetched.Add(ctx, 1, m.attributes(msg.Topic, msg.Partition))
+
+	if msg.HighWaterMark > 0 {
+		m.mu.Lock()
+		m.lag[topicPartition{topic: msg.Topic, partition: msg.Partition}] = msg.HighWaterMark - msg.Offset - 1
+		m.mu.Unlock()
+	}
+}
+
+func (m *clientMetrics) handle(ctx context.Context, msg kafka.Message, start time.Time, err error) {
+	attributes := m.attributes(originalTopic(msg), msg.Partition)
+	m.latency.Record(ctx, float64(time.Since(start))/1e6, attributes)
+	if err != nil {
+		m.failed.Add(ctx, 1, attributes)
+		return
+	}
+	m.processed.Add(ctx, 1, attributes)
+}
+
+func (m *clientMetrics) retry(ctx context.Context, msg kafka.Message) {
+	m.retried.Add(ctx, 1, m.attributes(originalTopic(msg), msg.Partition))
+}
+
+func (m *clientMetrics) moveToDLQ(ctx context.Context, msg kafka.Message) {
+	m.dlq.Add(ctx, 1, m.attributes(originalTopic(msg), msg.Partition))
+}
+
+func (m *clientMetrics) write(ctx context.Context, topic string, size int, err error) {
+	attributes := metric.WithAttributes(attribute.String("topic", topic))
+	m.writerBatch.Record(ctx, int64(size), attributes)
+	if err != nil {
+		m.writerErrors.Add(ctx, 1, attributes)
+	}
+}
+
+func (m *clientMetrics) observeLag(ctx context.Context, observer metric.Int64Observer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tp, lag := range m.lag {
+		observer.Observe(lag, m.attributes(tp.topic, tp.partition))
+	}
+	return nil
+}
+
+func handleMetricErr(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestClientMetrics(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(provider)
+	defer otel.SetMeterProvider(previous)
+
+	metrics := newClientMetrics("go-common")
+
+	msg := kafka.Message{Topic: "tester", Partition: 1, Offset: 10, HighWaterMark: 15}
+	metrics.fetch(ctx, msg)
+	metrics.handle(ctx, msg, time.Now(), nil)
+	metrics.handle(ctx, msg, time.Now(), errors.New("failed"))
+	metrics.retry(ctx, msg)
+	metrics.write(ctx, "tester", 3, nil)
+
+	data := metricdata.ResourceMetrics{}
+	assert.NoError(t, reader.Collect(ctx, &data))
+
+	values := map[string]int64{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				values[m.Name] = d.DataPoints[0].Value
+			case metricdata.Gauge[int64]:
+				values[m.Name] = d.DataPoints[0].Value
+			case metricdata.Histogram[int64]:
+				values[m.Name] = int64(d.DataPoints[0].Count)
+			case metricdata.Histogram[float64]:
+				values[m.Name] = int64(d.DataPoints[0].Count)
+			}
+		}
+	}
+
+	assert.Equal(t, int64(1), values["kafka.consumer.messages.fetched"])
+	assert.Equal(t, int64(1), values["kafka.consumer.messages.processed"])
+	assert.Equal(t, int64(1), values["kafka.consumer.messages.failed"])
+	assert.Equal(t, int64(1), values["kafka.consumer.messages.retried"])
+	assert.Equal(t, int64(2), values["kafka.consumer.handler.latency"])
+	assert.Equal(t, int64(1), values["kafka.writer.batch.size"])
+	assert.Equal(t, int64(4), values["kafka.consumer.lag"])
+}
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type committer interface {
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type pendingOffset struct {
+	msg    kafka.Message
+	done   bool
+	commit bool
+}
+
+type partitionOffsets struct {
+	mu      sync.Mutex
+	pending []*pendingOffset
+}
+
+// offsetTracker keeps the fetched offsets of every partition in fetch order, so
+// a commit is only sent once every previous offset of the partition finished.
+// Kafka tracks a single offset per partition, committing a later offset first
+// would implicitly commit the messages that are still being processed.
+type offsetTracker struct {
+	mu         sync.Mutex
+	committer  committer
+	partitions map[topicPartition]*partitionOffsets
+}
+
+func newOffsetTracker(c committer) *offsetTracker {
+	return &offsetTracker{
+		committer:  c,
+		partitions: make(map[topicPartition]*partitionOffsets),
+	}
+}
+
+func (t *offsetTracker) partition(m kafka.Message) *partitionOffsets {
+	tp := topicPartition{topic: m.Topic, partition: m.Partition}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[tp]
+	if !ok {
+		p = &partitionOffsets{}
+		t.partitions[tp] = p
+	}
+	return p
+}
+
+// track must be called in fetch order.
+func (t *offsetTracker) track(m kafka.Message) *pendingOffset {
+	p := t.partition(m)
+	offset := &pendingOffset{msg: m}
+
+	p.mu.Lock()
+	p.pending = append(p.pending, offset)
+	p.mu.Unlock()
+
+	return offset
+}
+
+// commit marks the offset as processed and requests a commit. The commit is
+// deferred until all previous offsets of the partition are done.
+func (t *offsetTracker) commit(ctx context.Context, offset *pendingOffset) error {
+	return t.complete(ctx, offset, true)
+}
+
+// done marks the offset as processed without requesting a commit, it is
+// committed together with the next committed offset of the partition.
+func (t *offsetTracker) done(ctx context.Context, offset *pendingOffset) error {
+	return t.complete(ctx, offset, false)
+}
+
+func (t *offsetTracker) complete(ctx context.Context, offset *pendingOffset, commit bool) error {
+	p := t.partition(offset.msg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if offset.done && (offset.commit || !commit) {
+		// completed before, e.g. msg.Commit() in the handler
+		return nil
+	}
+	offset.done = true
+	offset.commit = offset.commit || commit
+
+	var last *pendingOffset
+	n := 0
+	for ; n < len(p.pending) && p.pending[n].done; n++ {
+		if p.pending[n].commit {
+			last = p.pending[n]
+		}
+	}
+	p.pending = p.pending[n:]
+
+	if last == nil {
+		return nil
+	}
+	return t.committer.CommitMessages(ctx, last.msg)
+}
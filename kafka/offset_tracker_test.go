@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type committerStub struct {
+	offsets []int64
+}
+
+func (c *committerStub) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		c.offsets = append(c.offsets, m.Offset)
+	}
+	return nil
+}
+
+func TestOffsetTracker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Defer commit until previous offsets are done", func(t *testing.T) {
+		stub := &committerStub{}
+		tracker := newOffsetTracker(stub)
+
+		first := tracker.track(kafka.Message{Topic: "tester", Offset: 1})
+		second := tracker.track(kafka.Message{Topic: "tester", Offset: 2})
+
+		assert.NoError(t, tracker.commit(ctx, second))
+		assert.Empty(t, stub.offsets)
+
+		assert.NoError(t, tracker.commit(ctx, first))
+		assert.Equal(t, []int64{2}, stub.offsets)
+	})
+
+	t.Run("Done offsets are covered by the next commit", func(t *testing.T) {
+		stub := &committerStub{}
+		tracker := newOffsetTracker(stub)
+
+		first := tracker.track(kafka.Message{Topic: "tester", Offset: 1})
+		second := tracker.track(kafka.Message{Topic: "tester", Offset: 2})
+		third := tracker.track(kafka.Message{Topic: "tester", Offset: 3})
+
+		assert.NoError(t, tracker.done(ctx, first))
+		assert.NoError(t, tracker.commit(ctx, second))
+		assert.NoError(t, tracker.done(ctx, third))
+
+		assert.Equal(t, []int64{2}, stub.offsets)
+	})
+
+	t.Run("Repeated completions commit once", func(t *testing.T) {
+		stub := &committerStub{}
+		tracker := newOffsetTracker(stub)
+
+		first := tracker.track(kafka.Message{Topic: "tester", Offset: 1})
+		second := tracker.track(kafka.Message{Topic: "tester", Offset: 2})
+
+		assert.NoError(t, tracker.commit(ctx, first))
+		assert.NoError(t, tracker.commit(ctx, first))
+		assert.NoError(t, tracker.done(ctx, first))
+		assert.Equal(t, []int64{1}, stub.offsets)
+
+		assert.NoError(t, tracker.commit(ctx, second))
+		assert.NoError(t, tracker.done(ctx, second))
+		assert.Equal(t, []int64{1, 2}, stub.offsets)
+	})
+
+	t.Run("Partitions are tracked independently", func(t *testing.T) {
+		stub := &committerStub{}
+		tracker := newOffsetTracker(stub)
+
+		tracker.track(kafka.Message{Topic: "tester", Partition: 0, Offset: 1})
+		other := tracker.track(kafka.Message{Topic: "tester", Partition: 1, Offset: 7})
+
+		assert.NoError(t, tracker.commit(ctx, other))
+		assert.Equal(t, []int64{7}, stub.offsets)
+	})
+}
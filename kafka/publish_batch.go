@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+// PublishCallback is called once the message of PublishAsync was written,
+// err is nil on success. It runs on the writer goroutine and must not block,
+// hand the result over to a channel for longer work.
+type PublishCallback func(event Event, err error)
+
+type asyncMessage struct {
+	event    Event
+	callback PublishCallback
+}
+
+// PublishBatch publishes every event to topic with a single write, the
+// options are the same as for PublishWithOptions (the first one is used).
+// When only some messages failed the error is a kafka.WriteErrors holding the
+// error of every event at its index (nil for the published ones). The batch is
+// not retried, to avoid publishing the successful messages twice.
+func (k *Client) PublishBatch(ctx context.Context, topic string, events []Event, opts ...PublishOptions) error {
+	spanCtx, span := tracer.StartAndTraceWithData(ctx, "producer.PublishBatch", len(events))
+	defer span.End()
+
+	if !k.IsWriters() {
+		return tracer.TraceWithErr(spanCtx, errors.New("writers not created"))
+	}
+	if topic == "" {
+		return tracer.TraceWithErr(spanCtx, errors.New("topic not empty"))
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	opt := firstOptions(opts)
+	w, err := k.writerFor(opt.writerConfig())
+	if err != nil {
+		return tracer.TraceWithErr(spanCtx, err)
+	}
+
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		msg, err := k.newMessage(spanCtx, topic, event, opt)
+		if err != nil {
+			return tracer.TraceWithErr(spanCtx, err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	writeCtx, cancel := context.WithTimeout(spanCtx, opt.timeout())
+	defer cancel()
+
+	return tracer.TraceWithErr(spanCtx, k.write(writeCtx, w, msgs...))
+}
+
+// PublishAsync hands the event over to an asynchronous writer and returns
+// without waiting for kafka, so the writer can batch the messages of
+// concurrent producers. The result is reported to callback (may be nil).
+// An error is only returned when the message could not be queued, e.g. ctx
+// was cancelled. Call ClosePublisher to flush the queued messages.
+func (k *Client) PublishAsync(ctx context.Context, topic string, event Event, callback PublishCallback, opts ...PublishOptions) error {
+	if !k.IsWriters() {
+		return errors.New("writers not created")
+	}
+	if topic == "" {
+		return errors.New("topic not empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	opt := firstOptions(opts)
+	writerCfg := opt.writerConfig()
+	writerCfg.async = true
+	w, err := k.writerFor(writerCfg)
+	if err != nil {
+		return err
+	}
+
+	msg, err := k.newMessage(ctx, topic, event, opt)
+	if err != nil {
+		return err
+	}
+	msg.WriterData = asyncMessage{event: event, callback: callback}
+
+	return w.WriteMessages(ctx, msg)
+}
+
+// completeAsync is the Completion of the async writers.
+func (k *Client) completeAsync(messages []kafka.Message, err error) {
+	if len(messages) > 0 {
+		k.metrics.write(context.Background(), messages[0].Topic, len(messages), err)
+	}
+	common_utils.LogIfError(err)
+
+	for i, m := range messages {
+		async, ok := m.WriterData.(asyncMessage)
+		if !ok || async.callback == nil {
+			continue
+		}
+
+		msgErr := err
+		var writeErrs kafka.WriteErrors
+		if errors.As(err, &writeErrs) && i < len(writeErrs) {
+			msgErr = writeErrs[i]
+		}
+		async.callback(async.event, msgErr)
+	}
+}
+
+// ClosePublisher flushes the queued async messages and closes every writer.
+func (k *Client) ClosePublisher() error {
+	k.writersMu.Lock()
+	defer k.writersMu.Unlock()
+
+	var errs []error
+	for cfg, w := range k.writers {
+		errs = append(errs, w.Close())
+		delete(k.writers, cfg)
+	}
+	if w := k.writer.Swap(nil); w != nil {
+		errs = append(errs, w.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func firstOptions(opts []PublishOptions) PublishOptions {
+	if len(opts) == 0 {
+		return PublishOptions{}
+	}
+	return opts[0]
+}
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteAsync(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{}).(*Client)
+
+	results := make(map[string]error)
+	callback := func(event Event, err error) {
+		results[event.EventID] = err
+	}
+
+	messages := []kafka.Message{
+		{Topic: "tester", WriterData: asyncMessage{event: Event{EventID: "1"}, callback: callback}},
+		{Topic: "tester", WriterData: asyncMessage{event: Event{EventID: "2"}, callback: callback}},
+		{Topic: "tester"},
+	}
+
+	errWrite := errors.New("write failed")
+	client.completeAsync(messages, kafka.WriteErrors{nil, errWrite, nil})
+	assert.Equal(t, map[string]error{"1": nil, "2": errWrite}, results)
+
+	client.completeAsync(messages, kafka.LeaderNotAvailable)
+	assert.ErrorIs(t, results["1"], kafka.LeaderNotAvailable)
+	assert.ErrorIs(t, results["2"], kafka.LeaderNotAvailable)
+}
+
+func TestPublishAsyncCancelled(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{
+		KafkaBrokers: []string{"localhost:9092"},
+	}).(*Client)
+	assert.NoError(t, client.NewPublisher())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.PublishAsync(ctx, "tester", *NewEvent("tester", []byte("{}")), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, client.PublishBatch(context.Background(), "tester", nil))
+	assert.NoError(t, client.ClosePublisher())
+	assert.False(t, client.IsWriters())
+}
+
+func TestClosePublisherWhilePublishing(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{
+		KafkaBrokers: []string{"localhost:9092"},
+	}).(*Client)
+	assert.NoError(t, client.NewPublisher())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if client.IsWriters() {
+				client.writerFor(PublishOptions{Compression: kafka.Snappy}.writerConfig())
+			}
+		}
+	}()
+	assert.NoError(t, client.ClosePublisher())
+	<-done
+
+	assert.False(t, client.IsWriters())
+	_, err := client.writerFor(PublishOptions{}.writerConfig())
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	BalancerRoundRobin = "round-robin"
+	BalancerHash       = "hash"
+	BalancerMurmur2    = "murmur2"
+	BalancerLeastBytes = "least-bytes"
+)
+
+const defaultPublishTimeout = 10 * time.Second
+
+// PublishOptions customise a single PublishWithOptions call. The zero value
+// behaves like Publish.
+type PublishOptions struct {
+	// Key of the message, e.g. the aggregate ID. Defaults to the md5 of the
+	// payload.
+	Key []byte
+	// Balancer is one of BalancerRoundRobin (default), BalancerHash (fnv-1a,
+	// sarama compatible), BalancerMurmur2 (java client compatible) or
+	// BalancerLeastBytes. Use a key based balancer to keep the events of the
+	// same key on the same partition.
+	Balancer string
+	// Compression codec, kafka.Gzip, kafka.Snappy, kafka.Lz4 or kafka.Zstd.
+	// None by default.
+	Compression kafka.Compression
+	// RequiredAcks is kafka.RequireNone (default), kafka.RequireOne or
+	// kafka.RequireAll.
+	RequiredAcks kafka.RequiredAcks
+	// Headers are added to the message next to the origin and tracing headers.
+	Headers map[string]string
+	// Timeout of every write attempt, 10s by default.
+	Timeout time.Duration
+}
+
+// writerConfig holds the options which are set on the writer rather than the
+// message, a writer is created and cached per distinct writerConfig.
+type writerConfig struct {
+	balancer     string
+	compression  kafka.Compression
+	requiredAcks kafka.RequiredAcks
+	async        bool
+}
+
+func (o PublishOptions) writerConfig() writerConfig {
+	balancer := o.Balancer
+	if balancer == "" {
+		balancer = BalancerRoundRobin
+	}
+	return writerConfig{
+		balancer:     balancer,
+		compression:  o.Compression,
+		requiredAcks: o.RequiredAcks,
+	}
+}
+
+func newBalancer(name string) (kafka.Balancer, error) {
+	switch name {
+	case BalancerRoundRobin:
+		return &kafka.RoundRobin{}, nil
+	case BalancerHash:
+		return &kafka.Hash{}, nil
+	case BalancerMurmur2:
+		return &kafka.Murmur2Balancer{}, nil
+	case BalancerLeastBytes:
+		return &kafka.LeastBytes{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kafka balancer: %s", name)
+	}
+}
+
+// writerFor returns the writer of cfg, the default writer created by
+// NewPublisher is used for the default options.
+func (k *Client) writerFor(cfg writerConfig) (*kafka.Writer, error) {
+	defaultWriter := k.writer.Load()
+	if defaultWriter == nil {
+		return nil, errors.New("writers not created")
+	}
+	if cfg == (PublishOptions{}).writerConfig() {
+		return defaultWriter, nil
+	}
+
+	k.writersMu.Lock()
+	defer k.writersMu.Unlock()
+
+	// ClosePublisher resets the default writer under writersMu
+	if k.writer.Load() == nil {
+		return nil, errors.New("writers not created")
+	}
+	if w, ok := k.writers[cfg]; ok {
+		return w, nil
+	}
+
+	balancer, err := newBalancer(cfg.balancer)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &kafka.Writer{
+		Addr:                   defaultWriter.Addr,
+		Transport:              defaultWriter.Transport,
+		Balancer:               balancer,
+		BatchTimeout:           defaultWriter.BatchTimeout,
+		Compression:            cfg.compression,
+		RequiredAcks:           cfg.requiredAcks,
+		AllowAutoTopicCreation: defaultWriter.AllowAutoTopicCreation,
+	}
+	if cfg.async {
+		w.Async = true
+		w.Completion = k.completeAsync
+	}
+
+	if k.writers == nil {
+		k.writers = make(map[writerConfig]*kafka.Writer)
+	}
+	k.writers[cfg] = w
+	return w, nil
+}
+
+// newMessage encodes event into a message for topic with the key and headers
+// of opts, the tracing headers are taken from ctx.
+func (k *Client) newMessage(ctx context.Context, topic string, event Event, opts PublishOptions) (kafka.Message, error) {
+	eventPayload, err := common_utils.Marshal(event)
+	if err != nil {
+		return kafka.Message{}, errors.New("message of data sender can not marshal")
+	}
+	eventPayload, err = k.schemas.encode(ctx, topic, eventPayload)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	key := opts.Key
+	if len(key) == 0 {
+		key = []byte(hashMessage(eventPayload))
+	}
+
+	headers := tracer.GetKafkaTracingHeadersFromSpanCtx(ctx)
+	for name, value := range opts.Headers {
+		headers = append(headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+	headers = append(headers, kafka.Header{
+		Key:   HeaderOrigin,
+		Value: []byte(k.cfg.ServiceName),
+	})
+
+	return kafka.Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   eventPayload,
+		Headers: headers,
+	}, nil
+}
+
+func (o PublishOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return defaultPublishTimeout
+	}
+	return o.Timeout
+}
+
+// PublishWithOptions publishes the event like PublishWithTracer, with the key,
+// partitioning, compression, acks, headers and timeout taken from opts.
+func (k *Client) PublishWithOptions(ctx context.Context, topic string, event Event, opts PublishOptions) error {
+	spanCtx, span := tracer.StartAndTraceWithData(ctx, "producer.PublishMessage", event)
+	defer span.End()
+
+	if !k.IsWriters() {
+		return tracer.TraceWithErr(spanCtx, errors.New("writers not created"))
+	}
+	if topic == "" {
+		return tracer.TraceWithErr(spanCtx, errors.New("topic not empty"))
+	}
+
+	w, err := k.writerFor(opts.writerConfig())
+	if err != nil {
+		return tracer.TraceWithErr(spanCtx, err)
+	}
+
+	msg, err := k.newMessage(spanCtx, topic, event, opts)
+	if err != nil {
+		return tracer.TraceWithErr(spanCtx, err)
+	}
+
+	const retries = 3
+	for i := 0; i < retries; i++ {
+		writeCtx, cancel := context.WithTimeout(spanCtx, opts.timeout())
+		err = k.write(writeCtx, w, msg)
+		cancel()
+
+		if ctx.Err() == nil && (errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, context.DeadlineExceeded)) {
+			time.Sleep(time.Millisecond * 250)
+			continue
+		}
+		break
+	}
+	return tracer.TraceWithErr(spanCtx, err)
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterFor(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{
+		KafkaBrokers: []string{"localhost:9092"},
+	}).(*Client)
+	assert.NoError(t, client.NewPublisher())
+
+	w, err := client.writerFor(PublishOptions{}.writerConfig())
+	assert.NoError(t, err)
+	assert.Same(t, client.writer.Load(), w)
+
+	opts := PublishOptions{Balancer: BalancerMurmur2, Compression: kafka.Snappy, RequiredAcks: kafka.RequireAll}
+	w, err = client.writerFor(opts.writerConfig())
+	assert.NoError(t, err)
+	assert.IsType(t, &kafka.Murmur2Balancer{}, w.Balancer)
+	assert.Equal(t, kafka.Snappy, w.Compression)
+	assert.Equal(t, kafka.RequireAll, w.RequiredAcks)
+
+	cached, err := client.writerFor(opts.writerConfig())
+	assert.NoError(t, err)
+	assert.Same(t, w, cached)
+
+	_, err = client.writerFor(PublishOptions{Balancer: "unknown"}.writerConfig())
+	assert.Error(t, err)
+}
@@ -22,13 +22,14 @@ func (k *Client) NewPublisher() error {
 
 	w := &kafka.Writer{
 		Addr:                   kafka.TCP(k.cfg.KafkaBrokers...),
+		Transport:              k.newTransport(),
 		Balancer:               &kafka.RoundRobin{},
 		BatchTimeout:           15 * time.Millisecond,
 		AllowAutoTopicCreation: k.cfg.KafkaAutoTopicCreation,
 	}
 
 	common_utils.LogInfo("writer created")
-	k.writer = w
+	k.writer.Store(w)
 	return nil
 }
 
@@ -44,24 +45,27 @@ func (k *Client) Publish(ctx context.Context, topic string, event Event) error {
 	if err != nil {
 		return errors.New("message of data sender can not marshal")
 	}
+	eventPayload, err = k.schemas.encode(ctx, topic, eventPayload)
+	if err != nil {
+		return err
+	}
 	const retries = 3
 	for i := 0; i < retries; i++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		err = k.writer.WriteMessages(ctx, kafka.Message{
+		writeCtx, cancel := context.WithTimeout(ctx, defaultPublishTimeout)
+		err = k.writeMessages(writeCtx, kafka.Message{
 			Topic: topic,
 			Key:   []byte(hashMessage(eventPayload)),
 			Value: eventPayload,
 			Headers: []kafka.Header{
 				protocol.Header{
-					Key:   "origin",
+					Key:   HeaderOrigin,
 					Value: []byte(k.cfg.ServiceName),
 				},
 			},
 		})
+		cancel()
 
-		if errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, context.DeadlineExceeded) {
+		if ctx.Err() == nil && (errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, context.DeadlineExceeded)) {
 			time.Sleep(time.Millisecond * 250)
 			continue
 		}
@@ -94,27 +98,32 @@ func (k *Client) PublishWithTracer(ctx context.Context, topic string, event Even
 		span.SetStatus(codes.Error, err.Error())
 		return errors.New("message of data sender can not marshal")
 	}
+	eventPayload, err = k.schemas.encode(spanCtx, topic, eventPayload)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
 	headers := tracer.GetKafkaTracingHeadersFromSpanCtx(spanCtx)
 
 	headers = append(headers, kafka.Header{
-		Key:   "origin",
+		Key:   HeaderOrigin,
 		Value: []byte(k.cfg.ServiceName),
 	})
 
 	const retries = 3
 	for i := 0; i < retries; i++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		err = k.writer.WriteMessages(ctx, kafka.Message{
+		writeCtx, cancel := context.WithTimeout(spanCtx, defaultPublishTimeout)
+		err = k.writeMessages(writeCtx, kafka.Message{
 			Topic:   topic,
 			Key:     []byte(hashMessage(eventPayload)),
 			Value:   eventPayload,
 			Headers: headers,
 		})
+		cancel()
 		span.RecordError(err)
 
-		if errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, context.DeadlineExceeded) {
+		if ctx.Err() == nil && (errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, context.DeadlineExceeded)) {
 			time.Sleep(time.Millisecond * 250)
 			continue
 		}
@@ -129,6 +138,23 @@ func (k *Client) PublishWithTracer(ctx context.Context, topic string, event Even
 	return nil
 }
 
+// writeMessages writes with the default writer and records the writer metrics.
+func (k *Client) writeMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w := k.writer.Load()
+	if w == nil {
+		return errors.New("writers not created")
+	}
+	return k.write(ctx, w, msgs...)
+}
+
+func (k *Client) write(ctx context.Context, w *kafka.Writer, msgs ...kafka.Message) error {
+	err := w.WriteMessages(ctx, msgs...)
+	if len(msgs) > 0 {
+		k.metrics.write(ctx, msgs[0].Topic, len(msgs), err)
+	}
+	return err
+}
+
 func (k *Client) publishToDLQ(ctx context.Context, m kafka.Message) error {
 	if !k.IsWriters() {
 		return errors.New("writers not created")
@@ -137,13 +163,14 @@ func (k *Client) publishToDLQ(ctx context.Context, m kafka.Message) error {
 		return errors.New("topic not empty")
 	}
 
+	m.Headers = withOriginalHeaders(m)
 	m.Topic = k.cfg.KafkaDlqTopic
 
 	m.Headers = append(m.Headers, kafka.Header{
-		Key:   "origin",
+		Key:   HeaderOrigin,
 		Value: []byte(k.cfg.ServiceName),
 	})
 
-	err := k.writer.WriteMessages(ctx, m)
+	err := k.writeMessages(ctx, m)
 	return err
 }
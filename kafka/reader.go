@@ -0,0 +1,447 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+const readerMaxBytes = int(10e6) // 10MB
+
+// messageReader is implemented by *kafka.Reader and by the partition based
+// readers of the group-less and rebalance aware consumers.
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// newTopicReader returns the reader matching the consumer config of topic.
+func (k *Client) newTopicReader(topic string, dialer *kafka.Dialer) messageReader {
+	config := k.consumerConfig(topic)
+
+	switch {
+	case config.GroupLess:
+		common_utils.LogInfo(fmt.Sprintf("Listen: group-less, [%s]", topic))
+		return newPartitionsReader(k, topic, config, dialer)
+	case config.OnAssigned != nil || config.OnRevoked != nil:
+		common_utils.LogInfo(fmt.Sprintf("Listen: %s, [%s]", config.GroupID, topic))
+		return newGroupReader(k, topic, config, dialer)
+	default:
+		return k.newReader(topic, config, dialer)
+	}
+}
+
+// partitionReaders merges the messages of one reader per partition, the
+// partition readers are started by the first FetchMessage.
+type partitionReaders struct {
+	once     sync.Once
+	start    func(ctx context.Context)
+	messages chan kafka.Message
+	done     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newPartitionReaders(start func(ctx context.Context)) *partitionReaders {
+	return &partitionReaders{
+		start:    start,
+		messages: make(chan kafka.Message),
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *partitionReaders) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	p.once.Do(func() {
+		readCtx, cancel := context.WithCancel(context.Background())
+		p.cancel = cancel
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.start(readCtx)
+		}()
+		go func() {
+			p.wg.Wait()
+			close(p.done)
+		}()
+	})
+
+	select {
+	case m := <-p.messages:
+		return m, nil
+	case <-p.done:
+		return kafka.Message{}, io.EOF
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+// read fetches the partition of r until ctx is done, a fetch fails or bound
+// ends the partition.
+func (p *partitionReaders) read(ctx context.Context, r *kafka.Reader, bound *readBound, prepare func(m *kafka.Message)) {
+	defer r.Close()
+
+	for {
+		m, err := bound.fetch(ctx, r)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+				common_utils.LogError(fmt.Sprintf("failed fetch partition %d: %v", r.Config().Partition, err))
+			}
+			return
+		}
+
+		deliver, next := bound.check(m)
+		if deliver {
+			if prepare != nil {
+				prepare(&m)
+			}
+
+			select {
+			case p.messages <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if !next {
+			return
+		}
+	}
+}
+
+// boundedFetchIdle ends a partition read up to an end offset when no message
+// arrived for this long, the offsets left before the end offset are then
+// transaction markers or compacted away and are never fetched.
+const boundedFetchIdle = 10 * time.Second
+
+// readBound ends a partition read at the first message at or after until and
+// after the message before end. A zero until or a negative end does not
+// bound the read, a nil readBound reads until the reader is closed.
+type readBound struct {
+	until time.Time
+	end   int64
+}
+
+func (b *readBound) fetch(ctx context.Context, r *kafka.Reader) (kafka.Message, error) {
+	if b == nil || b.end < 0 {
+		return r.FetchMessage(ctx)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, boundedFetchIdle)
+	defer cancel()
+
+	m, err := r.FetchMessage(fetchCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return m, io.EOF
+	}
+	return m, err
+}
+
+// check reports whether m is delivered and whether the next message is
+// fetched.
+func (b *readBound) check(m kafka.Message) (deliver, next bool) {
+	if b == nil {
+		return true, true
+	}
+	if !b.until.IsZero() && !m.Time.Before(b.until) {
+		return false, false
+	}
+	if b.end < 0 {
+		return true, true
+	}
+	if m.Offset >= b.end {
+		return false, false
+	}
+	return true, m.Offset+1 < b.end
+}
+
+func (p *partitionReaders) close() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// partitionsReader reads the partitions of a topic without consumer group.
+type partitionsReader struct {
+	*partitionReaders
+	k      *Client
+	topic  string
+	config ConsumerConfig
+	dialer *kafka.Dialer
+}
+
+func newPartitionsReader(k *Client, topic string, config ConsumerConfig, dialer *kafka.Dialer) *partitionsReader {
+	r := &partitionsReader{k: k, topic: topic, config: config, dialer: dialer}
+	r.partitionReaders = newPartitionReaders(r.run)
+	return r
+}
+
+func (r *partitionsReader) run(ctx context.Context) {
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		common_utils.LogError(fmt.Sprintf("failed list partitions of %s: %v", r.topic, err))
+		return
+	}
+
+	// a replay up to an Until in the past ends at the end offsets of the
+	// partitions when it started, their newest messages may be older than
+	// Until
+	var offsets map[int]kafka.PartitionOffsets
+	if !r.config.Until.IsZero() && !r.config.Until.After(time.Now()) {
+		offsets, err = r.endOffsets(ctx)
+		if err != nil {
+			common_utils.LogError(fmt.Sprintf("failed list offsets of %s: %v", r.topic, err))
+			return
+		}
+	}
+
+	for _, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   r.k.cfg.KafkaBrokers,
+			Topic:     r.topic,
+			Partition: partition,
+			Dialer:    r.dialer,
+			MaxBytes:  readerMaxBytes,
+		})
+		if err := r.seek(ctx, reader); err != nil {
+			common_utils.LogError(fmt.Sprintf("failed seek partition %d of %s: %v", partition, r.topic, err))
+			reader.Close()
+			continue
+		}
+
+		bound := &readBound{until: r.config.Until, end: -1}
+		if offsets != nil {
+			partitionOffsets, ok := offsets[partition]
+			if !ok || caughtUp(reader.Offset(), partitionOffsets) {
+				reader.Close()
+				continue
+			}
+			bound.end = partitionOffsets.LastOffset
+		}
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.read(ctx, reader, bound, nil)
+		}()
+	}
+}
+
+func (r *partitionsReader) partitions(ctx context.Context) ([]int, error) {
+	if len(r.config.Partitions) > 0 {
+		return r.config.Partitions, nil
+	}
+
+	metadata, err := r.k.newAdminClient().Metadata(ctx, &kafka.MetadataRequest{Topics: []string{r.topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", r.topic)
+	}
+	if metadata.Topics[0].Error != nil {
+		return nil, metadata.Topics[0].Error
+	}
+
+	partitions := make([]int, 0, len(metadata.Topics[0].Partitions))
+	for _, p := range metadata.Topics[0].Partitions {
+		partitions = append(partitions, p.ID)
+	}
+	sort.Ints(partitions)
+	return partitions, nil
+}
+
+func (r *partitionsReader) endOffsets(ctx context.Context) (map[int]kafka.PartitionOffsets, error) {
+	partitions, err := r.k.partitionOffsets(ctx, r.k.newAdminClient(), r.topic)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]kafka.PartitionOffsets, len(partitions))
+	for _, p := range partitions {
+		offsets[p.Partition] = p
+	}
+	return offsets, nil
+}
+
+func (r *partitionsReader) seek(ctx context.Context, reader *kafka.Reader) error {
+	if !r.config.StartTime.IsZero() {
+		return reader.SetOffsetAt(ctx, r.config.StartTime)
+	}
+	if r.config.StartOffset == 0 {
+		return reader.SetOffset(kafka.FirstOffset)
+	}
+	return reader.SetOffset(r.config.StartOffset)
+}
+
+// caughtUp reports whether a reader at offset has nothing left to read up to
+// the end offset of its partition.
+func caughtUp(offset int64, partition kafka.PartitionOffsets) bool {
+	switch offset {
+	case kafka.FirstOffset:
+		offset = partition.FirstOffset
+	case kafka.LastOffset:
+		offset = partition.LastOffset
+	}
+	return offset >= partition.LastOffset
+}
+
+// CommitMessages is a no-op, group-less readers do not commit.
+func (r *partitionsReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return nil
+}
+
+func (r *partitionsReader) Close() error {
+	r.close()
+	return nil
+}
+
+// groupReader consumes a topic with a kafka.ConsumerGroup, which exposes the
+// generations and so the assigned and revoked partitions. The generation of
+// every message is kept in WriterData, so its offset is committed with the
+// generation it was fetched in.
+type groupReader struct {
+	*partitionReaders
+	k      *Client
+	topic  string
+	config ConsumerConfig
+	dialer *kafka.Dialer
+
+	mu    sync.Mutex
+	group *kafka.ConsumerGroup
+}
+
+func newGroupReader(k *Client, topic string, config ConsumerConfig, dialer *kafka.Dialer) *groupReader {
+	r := &groupReader{k: k, topic: topic, config: config, dialer: dialer}
+	r.partitionReaders = newPartitionReaders(r.run)
+	return r
+}
+
+func (r *groupReader) run(ctx context.Context) {
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:          r.config.GroupID,
+		Brokers:     r.k.cfg.KafkaBrokers,
+		Dialer:      r.dialer,
+		Topics:      []string{r.topic},
+		StartOffset: r.config.StartOffset,
+	})
+	if err != nil {
+		common_utils.LogError(fmt.Sprintf("failed create consumer group of %s: %v", r.topic, err))
+		return
+	}
+
+	r.mu.Lock()
+	r.group = group
+	r.mu.Unlock()
+	if ctx.Err() != nil {
+		common_utils.LogIfError(group.Close())
+		return
+	}
+
+	for {
+		gen, err := group.Next(ctx)
+		if err != nil {
+			if errors.Is(err, kafka.ErrGroupClosed) || ctx.Err() != nil {
+				return
+			}
+			common_utils.LogError(fmt.Sprintf("failed join consumer group of %s: %v", r.topic, err))
+			continue
+		}
+
+		assignments := gen.Assignments[r.topic]
+		partitions := make([]int, 0, len(assignments))
+		for _, assignment := range assignments {
+			partitions = append(partitions, assignment.ID)
+		}
+		sort.Ints(partitions)
+
+		common_utils.LogInfo(fmt.Sprintf("partitions %v of %s assigned, generation %d", partitions, r.topic, gen.ID))
+		if r.config.OnAssigned != nil {
+			r.config.OnAssigned(ctx, r.topic, partitions)
+		}
+
+		var fetchers sync.WaitGroup
+		for _, assignment := range assignments {
+			assignment := assignment
+			fetchers.Add(1)
+			gen.Start(func(genCtx context.Context) {
+				defer fetchers.Done()
+				r.readAssignment(genCtx, gen, assignment)
+			})
+		}
+
+		gen.Start(func(genCtx context.Context) {
+			<-genCtx.Done()
+			fetchers.Wait()
+
+			common_utils.LogInfo(fmt.Sprintf("partitions %v of %s revoked, generation %d", partitions, r.topic, gen.ID))
+			if r.config.OnRevoked != nil {
+				r.config.OnRevoked(context.WithoutCancel(ctx), r.topic, partitions)
+			}
+		})
+	}
+}
+
+func (r *groupReader) readAssignment(ctx context.Context, gen *kafka.Generation, assignment kafka.PartitionAssignment) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   r.k.cfg.KafkaBrokers,
+		Topic:     r.topic,
+		Partition: assignment.ID,
+		Dialer:    r.dialer,
+		MaxBytes:  readerMaxBytes,
+	})
+	if err := reader.SetOffset(assignment.Offset); err != nil {
+		common_utils.LogError(fmt.Sprintf("failed seek partition %d of %s: %v", assignment.ID, r.topic, err))
+		reader.Close()
+		return
+	}
+
+	r.read(ctx, reader, nil, func(m *kafka.Message) {
+		m.WriterData = gen
+	})
+}
+
+// CommitMessages commits the offsets with the generation of the messages,
+// commits of a generation which ended are rejected by kafka.
+func (r *groupReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	generations := make(map[*kafka.Generation]map[int]int64)
+	for _, m := range msgs {
+		gen, ok := m.WriterData.(*kafka.Generation)
+		if !ok {
+			return errors.New("message without consumer group generation")
+		}
+		if generations[gen] == nil {
+			generations[gen] = make(map[int]int64)
+		}
+		if offset := m.Offset + 1; offset > generations[gen][m.Partition] {
+			generations[gen][m.Partition] = offset
+		}
+	}
+
+	var errs []error
+	for gen, offsets := range generations {
+		errs = append(errs, gen.CommitOffsets(map[string]map[int]int64{r.topic: offsets}))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *groupReader) Close() error {
+	r.close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.group == nil {
+		return nil
+	}
+	return r.group.Close()
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumerConfig(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{KafkaGroupID: "tester"}).(*Client)
+	client.ConfigureTopic("Users.Replay.v1", ConsumerConfig{GroupLess: true})
+	client.ConfigureTopic("Users.Signup.v1", ConsumerConfig{
+		GroupID:    "signup",
+		OnAssigned: func(ctx context.Context, topic string, partitions []int) {},
+	})
+
+	assert.Equal(t, "tester", client.consumerConfig("Users.Change_Password.v1").GroupID)
+	assert.Equal(t, "signup", client.consumerConfig("Users.Signup.v1").GroupID)
+
+	dialer := client.newDialer()
+	replay := client.newTopicReader("Users.Replay.v1", dialer)
+	assert.IsType(t, &partitionsReader{}, replay)
+	assert.NoError(t, replay.Close())
+
+	signup := client.newTopicReader("Users.Signup.v1", dialer)
+	assert.IsType(t, &groupReader{}, signup)
+	assert.NoError(t, signup.Close())
+}
+
+func TestPartitionReadersFetch(t *testing.T) {
+	var p *partitionReaders
+	p = newPartitionReaders(func(ctx context.Context) {
+		p.messages <- kafka.Message{Offset: 1}
+	})
+
+	m, err := p.FetchMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), m.Offset)
+
+	_, err = p.FetchMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+	p.close()
+}
+
+func TestReadBoundCheck(t *testing.T) {
+	until := time.Now()
+	bound := &readBound{until: until, end: -1}
+
+	deliver, next := bound.check(kafka.Message{Time: until.Add(-time.Second)})
+	assert.True(t, deliver)
+	assert.True(t, next)
+	deliver, next = bound.check(kafka.Message{Time: until})
+	assert.False(t, deliver)
+	assert.False(t, next)
+
+	bound.until = time.Time{}
+	deliver, next = bound.check(kafka.Message{Time: until})
+	assert.True(t, deliver)
+	assert.True(t, next)
+
+	var unbounded *readBound
+	deliver, next = unbounded.check(kafka.Message{Time: until})
+	assert.True(t, deliver)
+	assert.True(t, next)
+}
+
+func TestReadBoundEndOffset(t *testing.T) {
+	// the newest messages are older than until, the read ends at the end
+	// offset
+	until := time.Now()
+	bound := &readBound{until: until, end: 3}
+	old := until.Add(-time.Hour)
+
+	deliver, next := bound.check(kafka.Message{Offset: 1, Time: old})
+	assert.True(t, deliver)
+	assert.True(t, next)
+	deliver, next = bound.check(kafka.Message{Offset: 2, Time: old})
+	assert.True(t, deliver)
+	assert.False(t, next)
+	deliver, next = bound.check(kafka.Message{Offset: 3, Time: old})
+	assert.False(t, deliver)
+	assert.False(t, next)
+
+	deliver, next = bound.check(kafka.Message{Offset: 1, Time: until})
+	assert.False(t, deliver)
+	assert.False(t, next)
+}
+
+func TestCaughtUp(t *testing.T) {
+	partition := kafka.PartitionOffsets{FirstOffset: 5, LastOffset: 10}
+
+	assert.False(t, caughtUp(kafka.FirstOffset, partition))
+	assert.True(t, caughtUp(kafka.LastOffset, partition))
+	assert.False(t, caughtUp(9, partition))
+	assert.True(t, caughtUp(10, partition))
+	assert.True(t, caughtUp(kafka.FirstOffset, kafka.PartitionOffsets{FirstOffset: 10, LastOffset: 10}))
+}
+
+func TestGroupReaderCommitWithoutGeneration(t *testing.T) {
+	r := &groupReader{topic: "tester"}
+	assert.Error(t, r.CommitMessages(context.Background(), kafka.Message{Offset: 1}))
+}
@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+var defaultRetryDelays = []string{"5s", "1m", "10m"}
+
+type retryTier struct {
+	name  string
+	delay time.Duration
+}
+
+// RetryTopic returns the name of the retry topic of topic for the given delay
+// read by the KafkaGroupID group, e.g. Users.Signup.v1.retry.5s.
+func RetryTopic(topic string, delay string) string {
+	return fmt.Sprintf("%s.retry.%s", topic, delay)
+}
+
+// GroupRetryTopic returns the retry topic of topic for a consumer group that
+// overrides KafkaGroupID, e.g. Users.Signup.v1.audit.retry.5s, so the group
+// does not read the retries of the KafkaGroupID group and vice versa.
+func GroupRetryTopic(topic string, group string, delay string) string {
+	return fmt.Sprintf("%s.%s.retry.%s", topic, group, delay)
+}
+
+// retryTopic returns the retry topic of topic for tier. Group-less topics
+// have no retry topic: the retries of a replay would be read by the live
+// group.
+func (k *Client) retryTopic(topic string, tier retryTier) (string, bool) {
+	config := k.consumerConfig(topic)
+	switch {
+	case config.GroupLess:
+		return "", false
+	case config.GroupID != k.cfg.KafkaGroupID:
+		return GroupRetryTopic(topic, config.GroupID, tier.name), true
+	default:
+		return RetryTopic(topic, tier.name), true
+	}
+}
+
+func parseRetryTiers(delays []string) []retryTier {
+	if len(delays) == 0 {
+		delays = defaultRetryDelays
+	}
+
+	tiers := make([]retryTier, 0, len(delays))
+	for _, name := range delays {
+		delay, err := time.ParseDuration(name)
+		if err != nil {
+			common_utils.LogError(fmt.Sprintf("invalid kafka retry delay: %s", name))
+			continue
+		}
+		tiers = append(tiers, retryTier{name: name, delay: delay})
+	}
+	return tiers
+}
+
+// tierFor returns the retry tier of the given attempt, the last tier is reused
+// when there are more attempts than tiers.
+func (k *Client) tierFor(attempt int) (retryTier, bool) {
+	return tierFor(k.retryTiers, attempt)
+}
+
+func tierFor(tiers []retryTier, attempt int) (retryTier, bool) {
+	if len(tiers) == 0 {
+		return retryTier{}, false
+	}
+
+	i := attempt - 2
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(tiers) {
+		i = len(tiers) - 1
+	}
+	return tiers[i], true
+}
+
+// publishToRetry republishes a failed message to the retry topic of its next
+// attempt, the retry consumer hands it to the handler again after the delay.
+func (k *Client) publishToRetry(ctx context.Context, m kafka.Message, attempt int, cause error) error {
+	if !k.IsWriters() {
+		return errors.New("writers not created")
+	}
+
+	tier, ok := k.tierFor(attempt)
+	if !ok {
+		return errors.New("retry topics not configured")
+	}
+	topic, ok := k.retryTopic(originalTopic(m), tier)
+	if !ok {
+		return errors.New("group-less topics are not retried")
+	}
+
+	headers := withOriginalHeaders(m)
+	headers = setHeader(headers, HeaderRetryAttempt, strconv.Itoa(attempt))
+	headers = setHeader(headers, HeaderError, cause.Error())
+
+	return k.writeMessages(ctx, kafka.Message{
+		Topic:   topic,
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	})
+}
+
+// waitRetryDelay blocks until the message of a retry topic is due. Every
+// message of a retry topic has the same delay, so blocking the reader keeps
+// the later messages waiting as well.
+func waitRetryDelay(ctx context.Context, m kafka.Message, delay time.Duration) bool {
+	wait := time.Until(m.Time.Add(delay))
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
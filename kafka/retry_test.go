@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTiers(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{
+		KafkaRetryDelays: []string{"5s", "1m", "invalid"},
+	}).(*Client)
+
+	assert.Len(t, client.retryTiers, 2)
+
+	tests := []struct {
+		name    string
+		attempt int
+		want    string
+	}{
+		{name: "First retry uses the first tier", attempt: 2, want: "5s"},
+		{name: "Second retry uses the second tier", attempt: 3, want: "1m"},
+		{name: "Later retries reuse the last tier", attempt: 7, want: "1m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier, ok := client.tierFor(tt.attempt)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, tier.name)
+		})
+	}
+
+	t.Run("Use default tiers when not configured", func(t *testing.T) {
+		client := NewKafkaClient(&common_utils.BaseConfig{}).(*Client)
+		assert.Len(t, client.retryTiers, len(defaultRetryDelays))
+	})
+
+	t.Run("Build retry topic name", func(t *testing.T) {
+		assert.Equal(t, "Users.Signup.v1.retry.5s", RetryTopic("Users.Signup.v1", "5s"))
+	})
+
+	t.Run("Build retry topic name of consumer group", func(t *testing.T) {
+		client := NewKafkaClient(&common_utils.BaseConfig{
+			KafkaBrokers: []string{"localhost:9092"},
+			KafkaGroupID: "tester",
+			KafkaTopics:  []string{"Users.Signup.v1", "Users.Audit.v1", "Users.Replay.v1"},
+		}).(*Client)
+		client.ConfigureTopic("Users.Audit.v1", ConsumerConfig{GroupID: "audit"})
+		client.ConfigureTopic("Users.Replay.v1", ConsumerConfig{GroupLess: true})
+		tier := retryTier{name: "5s", delay: 5 * time.Second}
+
+		topic, ok := client.retryTopic("Users.Signup.v1", tier)
+		assert.True(t, ok)
+		assert.Equal(t, "Users.Signup.v1.retry.5s", topic)
+
+		topic, ok = client.retryTopic("Users.Audit.v1", tier)
+		assert.True(t, ok)
+		assert.Equal(t, "Users.Audit.v1.audit.retry.5s", topic)
+
+		_, ok = client.retryTopic("Users.Replay.v1", tier)
+		assert.False(t, ok)
+
+		client.NewConsumer()
+		defer client.Close()
+		assert.Contains(t, client.readers, "Users.Audit.v1.audit.retry.5s")
+		assert.NotContains(t, client.readers, "Users.Audit.v1.retry.5s")
+		assert.NotContains(t, client.readers, "Users.Replay.v1.retry.5s")
+	})
+}
+
+func TestRetryHeaders(t *testing.T) {
+	m := kafka.Message{Topic: "tester", Partition: 2, Offset: 42}
+
+	t.Run("Record original position once", func(t *testing.T) {
+		m.Headers = withOriginalHeaders(m)
+		retried := kafka.Message{Topic: RetryTopic("tester", "5s"), Partition: 0, Offset: 1, Headers: m.Headers}
+		retried.Headers = withOriginalHeaders(retried)
+
+		assert.Equal(t, "tester", originalTopic(retried))
+		partition, _ := headerValue(retried.Headers, HeaderOriginalPartition)
+		assert.Equal(t, "2", partition)
+		offset, _ := headerValue(retried.Headers, HeaderOriginalOffset)
+		assert.Equal(t, "42", offset)
+	})
+
+	t.Run("Read retry attempt", func(t *testing.T) {
+		assert.Equal(t, 1, retryAttempt(kafka.Message{}))
+		retried := kafka.Message{Headers: setHeader(nil, HeaderRetryAttempt, "3")}
+		assert.Equal(t, 3, retryAttempt(retried))
+	})
+}
+
+func TestWaitRetryDelay(t *testing.T) {
+	t.Run("Return immediately when message is due", func(t *testing.T) {
+		m := kafka.Message{Time: time.Now().Add(-time.Minute)}
+		assert.True(t, waitRetryDelay(context.Background(), m, 5*time.Second))
+	})
+
+	t.Run("Stop waiting when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		m := kafka.Message{Time: time.Now()}
+		assert.False(t, waitRetryDelay(ctx, m, time.Minute))
+	})
+}
@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	common_utils "github.com/dispenal/go-common/utils"
+)
+
+// TypedHandlerFunc handles an event whose Data was decoded into T.
+type TypedHandlerFunc[T any] func(ctx context.Context, msg *Message, event *Event, data T) error
+
+type eventHandler func(ctx context.Context, msg *Message, event *Event) error
+
+// Router decodes the Event envelope of a message and dispatches it to the
+// handler registered for its EventType. Register handlers with Handle and
+// pass router.HandleMessage to Listen or ListenTopic.
+type Router struct {
+	codec    Codec
+	handlers map[EventType]eventHandler
+
+	// Fallback handles events without a registered handler, they are
+	// skipped when nil.
+	Fallback HandlerFunc
+	// Upcasters upgrade old event versions before decoding, defaults to the
+	// registry of RegisterUpcaster.
+	Upcasters *UpcasterRegistry
+}
+
+// NewRouter creates a router decoding event data with codec, JSONCodec is
+// used when codec is nil.
+func NewRouter(codec Codec) *Router {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	return &Router{
+		codec:     codec,
+		handlers:  make(map[EventType]eventHandler),
+		Upcasters: defaultUpcasters,
+	}
+}
+
+// Handle registers h for eventType. Events whose data can not be decoded into
+// T are moved to the DLQ without retry.
+func Handle[T any](r *Router, eventType EventType, h TypedHandlerFunc[T]) {
+	r.handlers[eventType] = func(ctx context.Context, msg *Message, event *Event) error {
+		data, err := decode[T](r.codec, event.Data)
+		if err != nil {
+			return Permanent(fmt.Errorf("decode %s data: %w", eventType, err))
+		}
+		return h(ctx, msg, event, data)
+	}
+}
+
+// decode allocates pointer types before decoding, codecs like ProtoCodec need
+// the message itself instead of a pointer to a nil message.
+func decode[T any](codec Codec, data []byte) (T, error) {
+	var value T
+	target := any(&value)
+
+	if t := reflect.TypeOf(value); t != nil && t.Kind() == reflect.Pointer {
+		value = reflect.New(t.Elem()).Interface().(T)
+		target = value
+	}
+
+	err := codec.Unmarshal(data, target)
+	return value, err
+}
+
+// HandleMessage implements HandlerFunc.
+func (r *Router) HandleMessage(ctx context.Context, msg *Message) error {
+	event := &Event{}
+	if err := common_utils.Unmarshal(msg.Body, event); err != nil {
+		return Permanent(fmt.Errorf("decode event: %w", err))
+	}
+
+	if r.Upcasters != nil {
+		if err := r.Upcasters.Upcast(event); err != nil {
+			return Permanent(err)
+		}
+	}
+
+	h, ok := r.handlers[event.EventType]
+	if !ok {
+		if r.Fallback != nil {
+			return r.Fallback(ctx, msg)
+		}
+		common_utils.LogDebug(fmt.Sprintf("no handler for event type %s, skipped", event.EventType))
+		return nil
+	}
+
+	return h(ctx, msg, event)
+}
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type userSignedUp struct {
+	Email string `json:"email"`
+}
+
+func newTestMessage(t *testing.T, event *Event) *Message {
+	body, err := common_utils.Marshal(event)
+	assert.NoError(t, err)
+	return &Message{Topic: "tester", Body: body}
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Route event to the handler of its type", func(t *testing.T) {
+		router := NewRouter(nil)
+
+		var got userSignedUp
+		Handle(router, "user.signed_up", func(ctx context.Context, msg *Message, event *Event, data userSignedUp) error {
+			got = data
+			return nil
+		})
+		Handle(router, "user.deleted", func(ctx context.Context, msg *Message, event *Event, data userSignedUp) error {
+			return errors.New("wrong handler")
+		})
+
+		data, _ := common_utils.Marshal(userSignedUp{Email: "user@mail.com"})
+		err := router.HandleMessage(ctx, newTestMessage(t, NewEvent("user.signed_up", data)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user@mail.com", got.Email)
+	})
+
+	t.Run("Skip events without handler", func(t *testing.T) {
+		router := NewRouter(nil)
+		err := router.HandleMessage(ctx, newTestMessage(t, NewEvent("unknown", []byte("{}"))))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Return permanent error when data can not be decoded", func(t *testing.T) {
+		router := NewRouter(nil)
+		Handle(router, "user.signed_up", func(ctx context.Context, msg *Message, event *Event, data userSignedUp) error {
+			return nil
+		})
+
+		err := router.HandleMessage(ctx, newTestMessage(t, NewEvent("user.signed_up", []byte("not json"))))
+
+		var permanent *PermanentError
+		assert.ErrorAs(t, err, &permanent)
+	})
+
+	t.Run("Return permanent error when envelope can not be decoded", func(t *testing.T) {
+		router := NewRouter(nil)
+		err := router.HandleMessage(ctx, &Message{Body: []byte("not json")})
+
+		var permanent *PermanentError
+		assert.ErrorAs(t, err, &permanent)
+	})
+
+	t.Run("Decode protobuf data", func(t *testing.T) {
+		router := NewRouter(ProtoCodec{})
+
+		var got string
+		Handle(router, "user.renamed", func(ctx context.Context, msg *Message, event *Event, data *wrapperspb.StringValue) error {
+			got = data.GetValue()
+			return nil
+		})
+
+		data, err := proto.Marshal(wrapperspb.String("new name"))
+		assert.NoError(t, err)
+
+		err = router.HandleMessage(ctx, newTestMessage(t, NewEvent("user.renamed", data)))
+		assert.NoError(t, err)
+		assert.Equal(t, "new name", got)
+	})
+}
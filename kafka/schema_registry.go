@@ -0,0 +1,397 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+)
+
+const (
+	wireMagicByte     = 0
+	wireHeaderSize    = 5
+	defaultSchemaType = "JSON"
+)
+
+var (
+	ErrNotWireFormat       = errors.New("message is not in schema registry wire format")
+	ErrSchemaNotFound      = errors.New("schema not found")
+	ErrIncompatibleSchema  = errors.New("schema is not compatible with the latest version")
+	ErrSchemaNotRegistered = errors.New("no schema registered for topic")
+)
+
+// SchemaRegistry stores the schemas of the topics, subjects follow the
+// Confluent topic name strategy (<topic>-value).
+type SchemaRegistry interface {
+	Register(ctx context.Context, subject string, schema string) (int, error)
+	Schema(ctx context.Context, id int) (string, error)
+	Latest(ctx context.Context, subject string) (int, string, error)
+	IsCompatible(ctx context.Context, subject string, schema string) (bool, error)
+}
+
+// ValueSubject returns the registry subject of the values of topic.
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+// EncodeWireFormat prefixes payload with the magic byte and the schema ID.
+func EncodeWireFormat(schemaID int, payload []byte) []byte {
+	data := make([]byte, wireHeaderSize+len(payload))
+	data[0] = wireMagicByte
+	binary.BigEndian.PutUint32(data[1:wireHeaderSize], uint32(schemaID))
+	copy(data[wireHeaderSize:], payload)
+	return data
+}
+
+// DecodeWireFormat splits data into the schema ID and the payload.
+func DecodeWireFormat(data []byte) (int, []byte, error) {
+	if len(data) < wireHeaderSize || data[0] != wireMagicByte {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:wireHeaderSize])), data[wireHeaderSize:], nil
+}
+
+// SetSchemaRegistry enables the wire format for Publish and the consumer.
+// Publishing to a topic requires a schema registered for its subject.
+func (k *Client) SetSchemaRegistry(registry SchemaRegistry) {
+	k.schemas.set(registry)
+}
+
+// RegisterSchema checks schema against the latest version of the subject of
+// topic and registers it, the returned ID is used by the next publishes.
+func (k *Client) RegisterSchema(ctx context.Context, topic string, schema string) (int, error) {
+	return k.schemas.register(ctx, topic, schema)
+}
+
+type schemaCache struct {
+	mu       sync.RWMutex
+	registry SchemaRegistry
+	ids      map[string]int
+	known    map[int]bool
+}
+
+func (c *schemaCache) set(registry SchemaRegistry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registry = registry
+	c.ids = make(map[string]int)
+	c.known = make(map[int]bool)
+}
+
+func (c *schemaCache) register(ctx context.Context, topic string, schema string) (int, error) {
+	registry := c.get()
+	if registry == nil {
+		return 0, errors.New("schema registry not configured")
+	}
+
+	subject := ValueSubject(topic)
+	compatible, err := registry.IsCompatible(ctx, subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	if !compatible {
+		return 0, fmt.Errorf("%s: %w", subject, ErrIncompatibleSchema)
+	}
+
+	id, err := registry.Register(ctx, subject, schema)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.ids[topic] = id
+	c.known[id] = true
+	c.mu.Unlock()
+
+	common_utils.LogInfo(fmt.Sprintf("schema %d registered for %s", id, subject))
+	return id, nil
+}
+
+func (c *schemaCache) get() SchemaRegistry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.registry
+}
+
+// encode prefixes the payload of topic with its schema ID, payloads are left
+// untouched without schema registry.
+func (c *schemaCache) encode(ctx context.Context, topic string, payload []byte) ([]byte, error) {
+	registry := c.get()
+	if registry == nil {
+		return payload, nil
+	}
+
+	c.mu.RLock()
+	id, ok := c.ids[topic]
+	c.mu.RUnlock()
+
+	if !ok {
+		latest, _, err := registry.Latest(ctx, ValueSubject(topic))
+		if errors.Is(err, ErrSchemaNotFound) {
+			return nil, fmt.Errorf("%s: %w", topic, ErrSchemaNotRegistered)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.ids[topic] = latest
+		c.known[latest] = true
+		c.mu.Unlock()
+		id = latest
+	}
+
+	return EncodeWireFormat(id, payload), nil
+}
+
+// decode strips the wire format and verifies the schema ID is known to the
+// registry. Payloads are left untouched without schema registry.
+func (c *schemaCache) decode(ctx context.Context, data []byte) ([]byte, int, error) {
+	registry := c.get()
+	if registry == nil {
+		return data, 0, nil
+	}
+
+	id, payload, err := DecodeWireFormat(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.mu.RLock()
+	known := c.known[id]
+	c.mu.RUnlock()
+
+	if !known {
+		if _, err := registry.Schema(ctx, id); err != nil {
+			return nil, id, fmt.Errorf("schema %d: %w", id, err)
+		}
+
+		c.mu.Lock()
+		c.known[id] = true
+		c.mu.Unlock()
+	}
+
+	return payload, id, nil
+}
+
+// SchemaRegistryClient talks to a Confluent compatible schema registry.
+type SchemaRegistryClient struct {
+	url        string
+	user       string
+	password   string
+	SchemaType string
+	HttpClient *http.Client
+}
+
+func NewSchemaRegistryClient(cfg *common_utils.BaseConfig) SchemaRegistry {
+	return &SchemaRegistryClient{
+		url:        strings.TrimSuffix(cfg.KafkaRegistryUrl, "/"),
+		user:       cfg.KafkaRegistryUser,
+		password:   cfg.KafkaRegistryPassword,
+		SchemaType: defaultSchemaType,
+		HttpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type schemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type schemaResponse struct {
+	ID           int    `json:"id"`
+	Schema       string `json:"schema"`
+	IsCompatible bool   `json:"is_compatible"`
+}
+
+func (s *SchemaRegistryClient) Register(ctx context.Context, subject string, schema string) (int, error) {
+	var res schemaResponse
+	err := s.do(ctx, http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)), s.request(schema), &res)
+	return res.ID, err
+}
+
+func (s *SchemaRegistryClient) Schema(ctx context.Context, id int) (string, error) {
+	var res schemaResponse
+	err := s.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &res)
+	return res.Schema, err
+}
+
+func (s *SchemaRegistryClient) Latest(ctx context.Context, subject string) (int, string, error) {
+	var res schemaResponse
+	err := s.do(ctx, http.MethodGet, fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)), nil, &res)
+	return res.ID, res.Schema, err
+}
+
+func (s *SchemaRegistryClient) IsCompatible(ctx context.Context, subject string, schema string) (bool, error) {
+	var res schemaResponse
+	err := s.do(ctx, http.MethodPost, fmt.Sprintf("/compatibility/subjects/%s/versions/latest", url.PathEscape(subject)), s.request(schema), &res)
+	if errors.Is(err, ErrSchemaNotFound) {
+		// first version of the subject
+		return true, nil
+	}
+	return res.IsCompatible, err
+}
+
+func (s *SchemaRegistryClient) request(schema string) *schemaRequest {
+	schemaType := s.SchemaType
+	if schemaType == "AVRO" {
+		// AVRO is the registry default and must be omitted for old versions
+		schemaType = ""
+	}
+	return &schemaRequest{Schema: schema, SchemaType: schemaType}
+}
+
+func (s *SchemaRegistryClient) do(ctx context.Context, method string, path string, body any, output any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := common_utils.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+
+	res, err := s.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrSchemaNotFound
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		message, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("schema registry %s %s: %d %s", method, path, res.StatusCode, string(message))
+	}
+
+	return common_utils.NewDecoder(res.Body).Decode(output)
+}
+
+// InMemorySchemaRegistry is a SchemaRegistry for tests.
+type InMemorySchemaRegistry struct {
+	mu       sync.RWMutex
+	schemas  map[int]string
+	subjects map[string][]int
+
+	// Compatible decides whether next can replace previous, defaults to
+	// IsBackwardCompatibleJSONSchema.
+	Compatible func(previous string, next string) bool
+}
+
+func NewInMemorySchemaRegistry() *InMemorySchemaRegistry {
+	return &InMemorySchemaRegistry{
+		schemas:    make(map[int]string),
+		subjects:   make(map[string][]int),
+		Compatible: IsBackwardCompatibleJSONSchema,
+	}
+}
+
+func (r *InMemorySchemaRegistry) Register(ctx context.Context, subject string, schema string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.subjects[subject] {
+		if r.schemas[id] == schema {
+			return id, nil
+		}
+	}
+
+	id := len(r.schemas) + 1
+	r.schemas[id] = schema
+	r.subjects[subject] = append(r.subjects[subject], id)
+	return id, nil
+}
+
+func (r *InMemorySchemaRegistry) Schema(ctx context.Context, id int) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schema, ok := r.schemas[id]
+	if !ok {
+		return "", ErrSchemaNotFound
+	}
+	return schema, nil
+}
+
+func (r *InMemorySchemaRegistry) Latest(ctx context.Context, subject string) (int, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := r.subjects[subject]
+	if len(ids) == 0 {
+		return 0, "", ErrSchemaNotFound
+	}
+	id := ids[len(ids)-1]
+	return id, r.schemas[id], nil
+}
+
+func (r *InMemorySchemaRegistry) IsCompatible(ctx context.Context, subject string, schema string) (bool, error) {
+	_, latest, err := r.Latest(ctx, subject)
+	if errors.Is(err, ErrSchemaNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return r.Compatible(latest, schema), nil
+}
+
+type jsonSchema struct {
+	Properties map[string]struct {
+		Type any `json:"type"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// IsBackwardCompatibleJSONSchema reports whether data written with previous
+// can be read with next: next must not require fields previous did not
+// require, and must not change the type of existing properties.
+func IsBackwardCompatibleJSONSchema(previous string, next string) bool {
+	if previous == next {
+		return true
+	}
+
+	var prev, nxt jsonSchema
+	if common_utils.Unmarshal([]byte(previous), &prev) != nil || common_utils.Unmarshal([]byte(next), &nxt) != nil {
+		return false
+	}
+
+	required := make(map[string]bool, len(prev.Required))
+	for _, field := range prev.Required {
+		required[field] = true
+	}
+	for _, field := range nxt.Required {
+		if !required[field] {
+			return false
+		}
+	}
+
+	for name, property := range nxt.Properties {
+		old, ok := prev.Properties[name]
+		if ok && old.Type != nil && property.Type != nil && fmt.Sprint(old.Type) != fmt.Sprint(property.Type) {
+			return false
+		}
+	}
+
+	return true
+}
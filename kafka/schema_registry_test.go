@@ -0,0 +1,123 @@
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	userSchemaV1 = `{"type":"object","properties":{"email":{"type":"string"}},"required":["email"]}`
+	userSchemaV2 = `{"type":"object","properties":{"email":{"type":"string"},"name":{"type":"string"}},"required":["email"]}`
+	userSchemaV3 = `{"type":"object","properties":{"email":{"type":"string"},"age":{"type":"integer"}},"required":["email","age"]}`
+)
+
+func TestWireFormat(t *testing.T) {
+	data := EncodeWireFormat(42, []byte(`{"email":"user@mail.com"}`))
+
+	id, payload, err := DecodeWireFormat(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, id)
+	assert.Equal(t, `{"email":"user@mail.com"}`, string(payload))
+
+	_, _, err = DecodeWireFormat([]byte(`{"email":"user@mail.com"}`))
+	assert.ErrorIs(t, err, ErrNotWireFormat)
+}
+
+func TestInMemorySchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewInMemorySchemaRegistry()
+
+	_, _, err := registry.Latest(ctx, "tester-value")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+
+	first, err := registry.Register(ctx, "tester-value", userSchemaV1)
+	assert.NoError(t, err)
+
+	again, err := registry.Register(ctx, "tester-value", userSchemaV1)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	compatible, err := registry.IsCompatible(ctx, "tester-value", userSchemaV2)
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	compatible, err = registry.IsCompatible(ctx, "tester-value", userSchemaV3)
+	assert.NoError(t, err)
+	assert.False(t, compatible)
+}
+
+func TestClientSchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+	client := NewKafkaClient(&common_utils.BaseConfig{}).(*Client)
+	client.SetSchemaRegistry(NewInMemorySchemaRegistry())
+
+	t.Run("Refuse to publish without registered schema", func(t *testing.T) {
+		_, err := client.schemas.encode(ctx, "tester", []byte("{}"))
+		assert.ErrorIs(t, err, ErrSchemaNotRegistered)
+	})
+
+	t.Run("Refuse incompatible schema", func(t *testing.T) {
+		_, err := client.RegisterSchema(ctx, "tester", userSchemaV1)
+		assert.NoError(t, err)
+
+		_, err = client.RegisterSchema(ctx, "tester", userSchemaV3)
+		assert.ErrorIs(t, err, ErrIncompatibleSchema)
+	})
+
+	t.Run("Encode and decode payload", func(t *testing.T) {
+		id, err := client.RegisterSchema(ctx, "tester", userSchemaV2)
+		assert.NoError(t, err)
+
+		data, err := client.schemas.encode(ctx, "tester", []byte(`{"email":"user@mail.com"}`))
+		assert.NoError(t, err)
+
+		payload, schemaID, err := client.schemas.decode(ctx, data)
+		assert.NoError(t, err)
+		assert.Equal(t, id, schemaID)
+		assert.Equal(t, `{"email":"user@mail.com"}`, string(payload))
+	})
+
+	t.Run("Reject unknown schema ID", func(t *testing.T) {
+		_, _, err := client.schemas.decode(ctx, EncodeWireFormat(999, []byte("{}")))
+		assert.ErrorIs(t, err, ErrSchemaNotFound)
+	})
+}
+
+func TestSchemaRegistryClient(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/subjects/tester-value/versions":
+			w.Write([]byte(`{"id":7}`))
+		case "/schemas/ids/7":
+			w.Write([]byte(`{"schema":"{}"}`))
+		case "/compatibility/subjects/tester-value/versions/latest":
+			w.Write([]byte(`{"is_compatible":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registry := NewSchemaRegistryClient(&common_utils.BaseConfig{KafkaRegistryUrl: server.URL})
+
+	id, err := registry.Register(ctx, "tester-value", "{}")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+
+	schema, err := registry.Schema(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", schema)
+
+	compatible, err := registry.IsCompatible(ctx, "tester-value", "{}")
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	_, _, err = registry.Latest(ctx, "tester-value")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
@@ -2,7 +2,9 @@ package kafka
 
 import (
 	"context"
-	"net"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	common_utils "github.com/dispenal/go-common/utils"
@@ -10,17 +12,18 @@ import (
 	"github.com/segmentio/kafka-go/topics"
 )
 
-func newClient(addr net.Addr) *kafka.Client {
+func (k *Client) newAdminClient() *kafka.Client {
 	client := &kafka.Client{
-		Addr:    addr,
-		Timeout: 5 * time.Second,
+		Addr:      kafka.TCP(k.cfg.KafkaBrokers...),
+		Timeout:   5 * time.Second,
+		Transport: k.newTransport(),
 	}
 
 	return client
 }
 
 func (k *Client) ListTopics() []kafka.Topic {
-	client := newClient(kafka.TCP(k.cfg.KafkaBrokers...))
+	client := k.newAdminClient()
 	topics, err := topics.List(context.TODO(), client)
 	if err != nil {
 		common_utils.LogError("list topic error: " + err.Error())
@@ -31,7 +34,7 @@ func (k *Client) ListTopics() []kafka.Topic {
 }
 
 func (k *Client) CreateTopic(topic string, numPart int) error {
-	client := newClient(kafka.TCP(k.cfg.KafkaBrokers...))
+	client := k.newAdminClient()
 
 	_, err := client.CreateTopics(context.TODO(), &kafka.CreateTopicsRequest{
 		Addr: kafka.TCP(k.cfg.KafkaBrokers...),
@@ -51,3 +54,278 @@ func (k *Client) CreateTopic(topic string, numPart int) error {
 	common_utils.LogInfo("topic created: " + topic)
 	return nil
 }
+
+// TopicSpec declares a topic for CreateTopicIfNotExists.
+type TopicSpec struct {
+	Topic string
+	// NumPartitions defaults to 1.
+	NumPartitions int
+	// ReplicationFactor defaults to KafkaReplicationFactor.
+	ReplicationFactor int
+	// Configs are the topic level configs, e.g. retention.ms,
+	// cleanup.policy=compact or min.insync.replicas.
+	Configs map[string]string
+}
+
+func (k *Client) topicConfig(spec TopicSpec) kafka.TopicConfig {
+	numPart := spec.NumPartitions
+	if numPart <= 0 {
+		numPart = 1
+	}
+	replicationFactor := spec.ReplicationFactor
+	if replicationFactor <= 0 {
+		replicationFactor = k.cfg.KafkaReplicationFactor
+	}
+
+	entries := make([]kafka.ConfigEntry, 0, len(spec.Configs))
+	for name, value := range spec.Configs {
+		entries = append(entries, kafka.ConfigEntry{ConfigName: name, ConfigValue: value})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ConfigName < entries[j].ConfigName
+	})
+
+	return kafka.TopicConfig{
+		Topic:             spec.Topic,
+		NumPartitions:     numPart,
+		ReplicationFactor: replicationFactor,
+		ConfigEntries:     entries,
+	}
+}
+
+// CreateTopicIfNotExists creates the topics which do not exist yet, existing
+// topics are left untouched. Meant to declare the topics of a service at
+// bootstrap.
+func (k *Client) CreateTopicIfNotExists(ctx context.Context, specs ...TopicSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	topics := make([]kafka.TopicConfig, 0, len(specs))
+	for _, spec := range specs {
+		topics = append(topics, k.topicConfig(spec))
+	}
+
+	resp, err := k.newAdminClient().CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: topics})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, topic := range topics {
+		err := resp.Errors[topic.Topic]
+		switch {
+		case err == nil:
+			common_utils.LogInfo("topic created: " + topic.Topic)
+		case errors.Is(err, kafka.TopicAlreadyExists):
+		default:
+			errs = append(errs, fmt.Errorf("create topic %s: %w", topic.Topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeleteTopics deletes the topics, unknown topics are reported as error.
+func (k *Client) DeleteTopics(ctx context.Context, topics ...string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+
+	resp, err := k.newAdminClient().DeleteTopics(ctx, &kafka.DeleteTopicsRequest{Topics: topics})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, topic := range topics {
+		if err := resp.Errors[topic]; err != nil {
+			errs = append(errs, fmt.Errorf("delete topic %s: %w", topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AddPartitions grows topic to count partitions in total, kafka does not
+// allow to reduce the number of partitions.
+func (k *Client) AddPartitions(ctx context.Context, topic string, count int) error {
+	resp, err := k.newAdminClient().CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+		Topics: []kafka.TopicPartitionsConfig{
+			{Name: topic, Count: int32(count)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Errors[topic]
+}
+
+// TopicDescription holds the partitions (with leader, replicas and ISR) and
+// the configs of a topic.
+type TopicDescription struct {
+	Topic      string
+	Partitions []kafka.Partition
+	Configs    map[string]string
+}
+
+// DescribeTopic returns the partitions and configs of topic, configNames
+// limits the configs returned (all by default).
+func (k *Client) DescribeTopic(ctx context.Context, topic string, configNames ...string) (*TopicDescription, error) {
+	client := k.newAdminClient()
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if metadata.Topics[0].Error != nil {
+		return nil, metadata.Topics[0].Error
+	}
+
+	resp, err := client.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{
+			{
+				ResourceType: kafka.ResourceTypeTopic,
+				ResourceName: topic,
+				ConfigNames:  configNames,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	description := &TopicDescription{
+		Topic:      topic,
+		Partitions: metadata.Topics[0].Partitions,
+		Configs:    make(map[string]string),
+	}
+	for _, resource := range resp.Resources {
+		if resource.Error != nil {
+			return nil, resource.Error
+		}
+		for _, entry := range resource.ConfigEntries {
+			description.Configs[entry.ConfigName] = entry.ConfigValue
+		}
+	}
+	return description, nil
+}
+
+// ListConsumerGroups returns the IDs of the consumer groups of the cluster.
+func (k *Client) ListConsumerGroups(ctx context.Context) ([]string, error) {
+	resp, err := k.newAdminClient().ListGroups(ctx, &kafka.ListGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	groups := make([]string, 0, len(resp.Groups))
+	for _, group := range resp.Groups {
+		groups = append(groups, group.GroupID)
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+// ResetOffsetsToEarliest commits the first offset of every partition of topic
+// for groupID. Like the other resets it fails while the group has active
+// members, stop the consumers first.
+func (k *Client) ResetOffsetsToEarliest(ctx context.Context, groupID, topic string) error {
+	return k.resetOffsets(ctx, groupID, topic, kafka.FirstOffset)
+}
+
+// ResetOffsetsToLatest commits the end offset of every partition of topic for
+// groupID, skipping every message not consumed yet.
+func (k *Client) ResetOffsetsToLatest(ctx context.Context, groupID, topic string) error {
+	return k.resetOffsets(ctx, groupID, topic, kafka.LastOffset)
+}
+
+// ResetOffsetsToTime commits the first offset written at or after at of every
+// partition of topic for groupID, partitions without such message are reset to
+// their end offset.
+func (k *Client) ResetOffsetsToTime(ctx context.Context, groupID, topic string, at time.Time) error {
+	return k.resetOffsets(ctx, groupID, topic, at.UnixMilli())
+}
+
+func (k *Client) resetOffsets(ctx context.Context, groupID, topic string, timestamp int64) error {
+	client := k.newAdminClient()
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return err
+	}
+	if len(metadata.Topics) == 0 {
+		return fmt.Errorf("topic %s not found", topic)
+	}
+	if metadata.Topics[0].Error != nil {
+		return metadata.Topics[0].Error
+	}
+
+	requests := make([]kafka.OffsetRequest, 0, len(metadata.Topics[0].Partitions)*2)
+	for _, p := range metadata.Topics[0].Partitions {
+		requests = append(requests, kafka.LastOffsetOf(p.ID))
+		if timestamp != kafka.LastOffset {
+			requests = append(requests, kafka.OffsetRequest{Partition: p.ID, Timestamp: timestamp})
+		}
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return err
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(offsets.Topics[topic]))
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return p.Error
+		}
+		commits = append(commits, kafka.OffsetCommit{
+			Partition: p.Partition,
+			Offset:    resetOffset(p, timestamp),
+		})
+	}
+
+	resp, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			errs = append(errs, fmt.Errorf("reset offset of %s/%d: %w", topic, p.Partition, p.Error))
+		}
+	}
+	if len(errs) == 0 {
+		common_utils.LogInfo(fmt.Sprintf("offsets of group %s reset on %s", groupID, topic))
+	}
+	return errors.Join(errs...)
+}
+
+// resetOffset picks the offset of p matching timestamp, falling back to the
+// end offset when no message was written after timestamp.
+func resetOffset(p kafka.PartitionOffsets, timestamp int64) int64 {
+	switch timestamp {
+	case kafka.FirstOffset:
+		return p.FirstOffset
+	case kafka.LastOffset:
+		return p.LastOffset
+	}
+
+	offset := p.LastOffset
+	for o := range p.Offsets {
+		if o >= 0 && o < offset {
+			offset = o
+		}
+	}
+	return offset
+}
@@ -0,0 +1,47 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicConfig(t *testing.T) {
+	client := NewKafkaClient(&common_utils.BaseConfig{KafkaReplicationFactor: 3}).(*Client)
+
+	cfg := client.topicConfig(TopicSpec{
+		Topic: "tester",
+		Configs: map[string]string{
+			"retention.ms":   "604800000",
+			"cleanup.policy": "compact",
+		},
+	})
+
+	assert.Equal(t, kafka.TopicConfig{
+		Topic:             "tester",
+		NumPartitions:     1,
+		ReplicationFactor: 3,
+		ConfigEntries: []kafka.ConfigEntry{
+			{ConfigName: "cleanup.policy", ConfigValue: "compact"},
+			{ConfigName: "retention.ms", ConfigValue: "604800000"},
+		},
+	}, cfg)
+}
+
+func TestResetOffset(t *testing.T) {
+	p := kafka.PartitionOffsets{
+		FirstOffset: 10,
+		LastOffset:  100,
+		Offsets:     map[int64]time.Time{42: time.Now()},
+	}
+
+	assert.Equal(t, int64(10), resetOffset(p, kafka.FirstOffset))
+	assert.Equal(t, int64(100), resetOffset(p, kafka.LastOffset))
+	assert.Equal(t, int64(42), resetOffset(p, time.Now().UnixMilli()))
+
+	p.Offsets = map[int64]time.Time{-1: {}}
+	assert.Equal(t, int64(100), resetOffset(p, time.Now().UnixMilli()))
+}
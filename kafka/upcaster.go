@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Upcaster transforms the data of an event from one version to the next.
+type Upcaster func(data []byte) ([]byte, error)
+
+// UpcasterRegistry holds the upcasters per EventType, so consumers can still
+// handle events written with an older version of the data.
+type UpcasterRegistry struct {
+	mu        sync.RWMutex
+	upcasters map[EventType]map[uint64]Upcaster
+}
+
+var defaultUpcasters = NewUpcasterRegistry()
+
+func NewUpcasterRegistry() *UpcasterRegistry {
+	return &UpcasterRegistry{
+		upcasters: make(map[EventType]map[uint64]Upcaster),
+	}
+}
+
+// Register adds the upcaster of eventType from fromVersion to fromVersion+1.
+func (r *UpcasterRegistry) Register(eventType EventType, fromVersion uint64, upcaster Upcaster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.upcasters[eventType] == nil {
+		r.upcasters[eventType] = make(map[uint64]Upcaster)
+	}
+	r.upcasters[eventType][fromVersion] = upcaster
+}
+
+// LatestVersion returns the version the events of eventType are upcasted to.
+func (r *UpcasterRegistry) LatestVersion(eventType EventType) uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	version := uint64(0)
+	for _, ok := r.upcasters[eventType][version]; ok; _, ok = r.upcasters[eventType][version] {
+		version++
+	}
+	return version
+}
+
+// Upcast applies the upcasters of the event type one version after the other,
+// starting at event.Version, until no upcaster is registered.
+func (r *UpcasterRegistry) Upcast(event *Event) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for {
+		upcaster, ok := r.upcasters[event.EventType][event.Version]
+		if !ok {
+			return nil
+		}
+
+		data, err := upcaster(event.Data)
+		if err != nil {
+			return fmt.Errorf("upcast %s from version %d: %w", event.EventType, event.Version, err)
+		}
+		event.Data = data
+		event.Version++
+	}
+}
+
+// RegisterUpcaster registers the upcaster on the default registry, which is
+// used by NewEvent and NewRouter.
+func RegisterUpcaster(eventType EventType, fromVersion uint64, upcaster Upcaster) {
+	defaultUpcasters.Register(eventType, fromVersion, upcaster)
+}
+
+// Upcast upcasts the event with the default registry.
+func Upcast(event *Event) error {
+	return defaultUpcasters.Upcast(event)
+}
@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpcasterRegistry(t *testing.T) {
+	registry := NewUpcasterRegistry()
+	registry.Register("user.signed_up", 0, func(data []byte) ([]byte, error) {
+		var v0 struct {
+			Mail string `json:"mail"`
+		}
+		if err := common_utils.Unmarshal(data, &v0); err != nil {
+			return nil, err
+		}
+		return common_utils.Marshal(userSignedUp{Email: v0.Mail})
+	})
+	registry.Register("user.signed_up", 1, func(data []byte) ([]byte, error) {
+		return data, nil
+	})
+
+	t.Run("Return latest version", func(t *testing.T) {
+		assert.Equal(t, uint64(2), registry.LatestVersion("user.signed_up"))
+		assert.Equal(t, uint64(0), registry.LatestVersion("unknown"))
+	})
+
+	t.Run("Upcast event to latest version", func(t *testing.T) {
+		event := NewVersionedEvent("user.signed_up", 0, []byte(`{"mail":"user@mail.com"}`))
+
+		assert.NoError(t, registry.Upcast(event))
+		assert.Equal(t, uint64(2), event.Version)
+		assert.JSONEq(t, `{"email":"user@mail.com"}`, string(event.Data))
+	})
+
+	t.Run("Keep current events untouched", func(t *testing.T) {
+		event := NewVersionedEvent("user.signed_up", 2, []byte(`{"email":"user@mail.com"}`))
+
+		assert.NoError(t, registry.Upcast(event))
+		assert.Equal(t, uint64(2), event.Version)
+	})
+
+	t.Run("Return permanent error from router when upcast fails", func(t *testing.T) {
+		failing := NewUpcasterRegistry()
+		failing.Register("user.signed_up", 0, func(data []byte) ([]byte, error) {
+			return nil, errors.New("broken")
+		})
+
+		router := NewRouter(nil)
+		router.Upcasters = failing
+		Handle(router, "user.signed_up", func(ctx context.Context, msg *Message, event *Event, data userSignedUp) error {
+			return nil
+		})
+
+		err := router.HandleMessage(context.Background(), newTestMessage(t, NewVersionedEvent("user.signed_up", 0, []byte("{}"))))
+
+		var permanent *PermanentError
+		assert.ErrorAs(t, err, &permanent)
+	})
+
+	t.Run("Upcast in router before decoding", func(t *testing.T) {
+		router := NewRouter(nil)
+		router.Upcasters = registry
+
+		var got userSignedUp
+		Handle(router, "user.signed_up", func(ctx context.Context, msg *Message, event *Event, data userSignedUp) error {
+			got = data
+			return nil
+		})
+
+		err := router.HandleMessage(context.Background(), newTestMessage(t, NewVersionedEvent("user.signed_up", 0, []byte(`{"mail":"user@mail.com"}`))))
+		assert.NoError(t, err)
+		assert.Equal(t, "user@mail.com", got.Email)
+	})
+}
@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dispenal/go-common/kafka"
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const defaultTable = "kafka_outbox"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id            BIGSERIAL PRIMARY KEY,
+	topic         TEXT NOT NULL,
+	event_id      TEXT NOT NULL,
+	payload       BYTEA NOT NULL,
+	trace_carrier BYTEA,
+	attempts      INT NOT NULL DEFAULT 0,
+	last_error    TEXT,
+	created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent_at       TIMESTAMPTZ,
+	failed_at     TIMESTAMPTZ
+);
+ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS %[2]s_unsent_idx ON %[1]s (id) WHERE sent_at IS NULL;
+`
+
+// Outbox stores kafka events in the same postgres transaction as the business
+// data, the Relay publishes them once the transaction is committed.
+type Outbox interface {
+	Migrate(ctx context.Context, pgxPool *pgxpool.Pool) error
+	Save(ctx context.Context, tx pgx.Tx, topic string, events ...kafka.Event) error
+}
+
+type OutboxImpl struct {
+	table string
+}
+
+func NewOutbox(config *common_utils.BaseConfig) Outbox {
+	return &OutboxImpl{table: tableName(config)}
+}
+
+func tableName(config *common_utils.BaseConfig) string {
+	if config.OutboxTable == "" {
+		return defaultTable
+	}
+	if !common_utils.ValidTableName(config.OutboxTable) {
+		common_utils.PanicAppError(fmt.Sprintf("invalid outbox table name: %s", config.OutboxTable), 500)
+	}
+	return config.OutboxTable
+}
+
+// Migrate creates the outbox table when it does not exist, and adds the
+// columns of newer versions to an existing one.
+func (o *OutboxImpl) Migrate(ctx context.Context, pgxPool *pgxpool.Pool) error {
+	index := common_utils.IndexName(o.table)
+	_, err := pgxPool.Exec(ctx, fmt.Sprintf(schema, o.table, index))
+	return err
+}
+
+// Save writes the events into the outbox inside tx, usually the tx of
+// common_utils.ExecTx. The trace context of ctx is stored with every event, so
+// the publish span is linked to the request which created the event.
+//
+//	err := common_utils.ExecTx(ctx, pool, func(tx pgx.Tx) error {
+//		if err := repo.CreateUser(ctx, tx, user); err != nil {
+//			return err
+//		}
+//		return outbox.Save(ctx, tx, "Users.Signup.v1", *event)
+//	})
+func (o *OutboxImpl) Save(ctx context.Context, tx pgx.Tx, topic string, events ...kafka.Event) error {
+	if topic == "" {
+		return errors.New("topic not empty")
+	}
+
+	carrier := tracer.ExtractTextMapCarrierBytes(ctx)
+
+	batch := &pgx.Batch{}
+	query := fmt.Sprintf("INSERT INTO %s (topic, event_id, payload, trace_carrier) VALUES ($1, $2, $3, $4)", o.table)
+	for _, event := range events {
+		payload, err := common_utils.Marshal(event)
+		if err != nil {
+			return err
+		}
+		batch.Queue(query, topic, event.EventID, payload, carrier)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dispenal/go-common/kafka"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableName(t *testing.T) {
+	assert.Equal(t, defaultTable, tableName(&common_utils.BaseConfig{}))
+	assert.Equal(t, "app.outbox", tableName(&common_utils.BaseConfig{OutboxTable: "app.outbox"}))
+	assert.Panics(t, func() {
+		tableName(&common_utils.BaseConfig{OutboxTable: "outbox; DROP TABLE users"})
+	})
+}
+
+func TestOutboxSave(t *testing.T) {
+	ctx := context.Background()
+	config := &common_utils.BaseConfig{}
+	pool, outbox := newTestOutbox(t, config)
+
+	err := common_utils.ExecTx(ctx, pool, func(tx pgx.Tx) error {
+		return outbox.Save(ctx, tx, "", kafka.Event{})
+	})
+	assert.Error(t, err)
+
+	first := kafka.NewEvent("Tested", []byte(`{"n":1}`))
+	second := kafka.NewEvent("Tested", []byte(`{"n":2}`))
+	err = common_utils.ExecTx(ctx, pool, func(tx pgx.Tx) error {
+		return outbox.Save(ctx, tx, "Tests.v1", *first, *second)
+	})
+	assert.NoError(t, err)
+
+	rows := selectOutboxRows(t, pool, config.OutboxTable)
+	assert.Len(t, rows, 2)
+	for _, row := range rows {
+		assert.Equal(t, "Tests.v1", row.topic)
+		assert.False(t, row.sent)
+	}
+}
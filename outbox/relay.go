@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dispenal/go-common/kafka"
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const defaultMaxAttempts = 5
+
+// Relay polls the outbox table and publishes the unsent events with
+// PublishWithTracer. Rows are locked with FOR UPDATE SKIP LOCKED while they
+// are published, so multiple relay instances can run side by side. Delivery
+// is at least once: an event is published again when marking it as sent
+// fails.
+//
+// An event failing OUTBOX_MAX_ATTEMPTS times is parked: its failed_at is set
+// and the relay moves on to the following events, so it does not hold the
+// outbox back. Set failed_at back to NULL to publish it again.
+type Relay struct {
+	table        string
+	pgxPool      *pgxpool.Pool
+	client       kafka.IClient
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+}
+
+func NewRelay(config *common_utils.BaseConfig, pgxPool *pgxpool.Pool, client kafka.IClient) *Relay {
+	relay := &Relay{
+		table:        tableName(config),
+		pgxPool:      pgxPool,
+		client:       client,
+		pollInterval: config.OutboxPollInterval,
+		batchSize:    config.OutboxBatchSize,
+		maxAttempts:  config.OutboxMaxAttempts,
+	}
+
+	if relay.pollInterval <= 0 {
+		relay.pollInterval = common_utils.DefaultPollInterval
+	}
+	if relay.batchSize <= 0 {
+		relay.batchSize = common_utils.DefaultPollBatchSize
+	}
+	if relay.maxAttempts <= 0 {
+		relay.maxAttempts = defaultMaxAttempts
+	}
+
+	return relay
+}
+
+// Run relays the outbox until ctx is cancelled, see common_utils.RunPoll.
+func (r *Relay) Run(ctx context.Context) error {
+	return common_utils.RunPoll(ctx, "relay outbox", r.pollInterval, r.batchSize, r.RelayBatch)
+}
+
+type outboxRow struct {
+	id      int64
+	topic   string
+	payload []byte
+	carrier []byte
+}
+
+// RelayBatch publishes one batch of unsent events in id order and returns the
+// number of sent events. The batch stops at the first failed event to keep
+// the order of the following events, unless the event is parked. The sent
+// events and the attempt of the failed one are committed before its error is
+// returned.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	var sent int
+	var publishErr error
+
+	err := common_utils.ExecTx(ctx, r.pgxPool, func(tx pgx.Tx) error {
+		var err error
+		sent, publishErr, err = r.relayTx(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return sent, publishErr
+}
+
+// relayTx publishes the locked unsent events and records the result in tx. The
+// returned error aborts tx, the publish error does not.
+func (r *Relay) relayTx(ctx context.Context, tx pgx.Tx) (sent int, publishErr error, err error) {
+	rows, err := r.lockUnsent(ctx, tx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	sentIDs := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		if err := r.publish(ctx, row); err != nil {
+			parked, recordErr := r.recordAttempt(ctx, tx, row, err)
+			if recordErr != nil {
+				return 0, nil, recordErr
+			}
+			if !parked {
+				publishErr = err
+				break
+			}
+			common_utils.LogError(fmt.Sprintf("parked outbox event %d of %s after %d attempts: %v", row.id, row.topic, r.maxAttempts, err))
+			continue
+		}
+		sentIDs = append(sentIDs, row.id)
+	}
+
+	if len(sentIDs) > 0 {
+		_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET sent_at = now() WHERE id = ANY($1)", r.table), sentIDs)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return len(sentIDs), publishErr, nil
+}
+
+// recordAttempt counts the failed attempt of row and parks it once it reached
+// maxAttempts, it reports whether row was parked.
+func (r *Relay) recordAttempt(ctx context.Context, tx pgx.Tx, row outboxRow, publishErr error) (bool, error) {
+	var parked bool
+	err := tx.QueryRow(ctx, fmt.Sprintf(`
+UPDATE %s SET
+	attempts = attempts + 1,
+	last_error = $2,
+	failed_at = CASE WHEN attempts + 1 >= $3 THEN now() END
+WHERE id = $1
+RETURNING failed_at IS NOT NULL`, r.table),
+		row.id, publishErr.Error(), r.maxAttempts,
+	).Scan(&parked)
+	return parked, err
+}
+
+func (r *Relay) lockUnsent(ctx context.Context, tx pgx.Tx) ([]outboxRow, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		"SELECT id, topic, payload, trace_carrier FROM %s WHERE sent_at IS NULL AND failed_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED",
+		r.table,
+	), r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (outboxRow, error) {
+		var o outboxRow
+		err := row.Scan(&o.id, &o.topic, &o.payload, &o.carrier)
+		return o, err
+	})
+}
+
+func (r *Relay) publish(ctx context.Context, row outboxRow) error {
+	event := kafka.Event{}
+	if err := common_utils.Unmarshal(row.payload, &event); err != nil {
+		return err
+	}
+
+	carrier := propagation.MapCarrier{}
+	if len(row.carrier) > 0 {
+		common_utils.LogIfError(common_utils.Unmarshal(row.carrier, &carrier))
+	}
+
+	spanCtx, span := tracer.StartAndTraceKafkaProducer(ctx, carrier, "outbox.Relay")
+	defer span.End()
+
+	return tracer.TraceWithErr(spanCtx, r.client.PublishWithTracer(spanCtx, row.topic, event))
+}
+
+// Purge deletes the events sent before the given time.
+func (r *Relay) Purge(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pgxPool.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE sent_at IS NOT NULL AND sent_at < $1", r.table), before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
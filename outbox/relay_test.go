@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dispenal/go-common/kafka"
+	mock_kafka "github.com/dispenal/go-common/kafka/mock"
+	"github.com/dispenal/go-common/postgres/pgtest"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// newTestOutbox migrates an outbox with its own table in the postgres of
+// test.env, the test is skipped when it is not reachable.
+func newTestOutbox(t *testing.T, config *common_utils.BaseConfig) (*pgxpool.Pool, Outbox) {
+	pool := pgtest.NewPool(t, "../")
+	config.OutboxTable = pgtest.TableName(t, pool, "outbox")
+
+	outbox := NewOutbox(config)
+	assert.NoError(t, outbox.Migrate(context.Background(), pool))
+	return pool, outbox
+}
+
+type testOutboxRow struct {
+	topic     string
+	attempts  int
+	lastError *string
+	sent      bool
+	parked    bool
+}
+
+func selectOutboxRows(t *testing.T, pool *pgxpool.Pool, table string) []testOutboxRow {
+	rows, err := pool.Query(context.Background(), fmt.Sprintf(
+		"SELECT topic, attempts, last_error, sent_at IS NOT NULL, failed_at IS NOT NULL FROM %s ORDER BY id", table,
+	))
+	assert.NoError(t, err)
+
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (testOutboxRow, error) {
+		var r testOutboxRow
+		err := row.Scan(&r.topic, &r.attempts, &r.lastError, &r.sent, &r.parked)
+		return r, err
+	})
+	assert.NoError(t, err)
+	return result
+}
+
+func TestRelayBatch(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+
+	config := &common_utils.BaseConfig{OutboxMaxAttempts: 2}
+	pool, outbox := newTestOutbox(t, config)
+	err := common_utils.ExecTx(ctx, pool, func(tx pgx.Tx) error {
+		for _, topic := range []string{"ok", "ok", "poison", "ok"} {
+			if err := outbox.Save(ctx, tx, topic, *kafka.NewEvent("Tested", []byte("{}"))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	publishErr := errors.New("broker unavailable")
+	client := mock_kafka.NewMockIClient(ctrl)
+	client.EXPECT().PublishWithTracer(gomock.Any(), "ok", gomock.Any()).Return(nil).AnyTimes()
+	client.EXPECT().PublishWithTracer(gomock.Any(), "poison", gomock.Any()).Return(publishErr).AnyTimes()
+
+	relay := NewRelay(config, pool, client)
+
+	t.Run("Commit the sent rows before a failed one", func(t *testing.T) {
+		sent, err := relay.RelayBatch(ctx)
+		assert.ErrorIs(t, err, publishErr)
+		assert.Equal(t, 2, sent)
+
+		rows := selectOutboxRows(t, pool, config.OutboxTable)
+		if !assert.Len(t, rows, 4) {
+			return
+		}
+		assert.True(t, rows[0].sent)
+		assert.True(t, rows[1].sent)
+		assert.False(t, rows[2].sent)
+		assert.False(t, rows[3].sent)
+	})
+
+	t.Run("Count the attempts of the failed row", func(t *testing.T) {
+		rows := selectOutboxRows(t, pool, config.OutboxTable)
+		if !assert.Len(t, rows, 4) {
+			return
+		}
+		assert.Equal(t, 1, rows[2].attempts)
+		assert.False(t, rows[2].parked)
+		if assert.NotNil(t, rows[2].lastError) {
+			assert.Equal(t, publishErr.Error(), *rows[2].lastError)
+		}
+	})
+
+	t.Run("Park the failed row and send the following ones", func(t *testing.T) {
+		sent, err := relay.RelayBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		rows := selectOutboxRows(t, pool, config.OutboxTable)
+		if !assert.Len(t, rows, 4) {
+			return
+		}
+		assert.Equal(t, 2, rows[2].attempts)
+		assert.True(t, rows[2].parked)
+		assert.False(t, rows[2].sent)
+		assert.True(t, rows[3].sent)
+
+		sent, err = relay.RelayBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent)
+	})
+}
@@ -0,0 +1,56 @@
+// Package pgtest connects integration tests to the postgres of test.env.
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dispenal/go-common/postgres"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewPool connects to the postgres of the test.env in configPath, the test is
+// skipped when it is not reachable. The pool is closed with the test.
+func NewPool(t testing.TB, configPath string) *pgxpool.Pool {
+	t.Helper()
+
+	config, err := common_utils.LoadBaseConfig(configPath, "test")
+	if err != nil {
+		t.Fatalf("load test config: %v", err)
+	}
+
+	pool, err := postgres.NewPgxConn(config)
+	if err != nil {
+		t.Skipf("postgres not available: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		t.Skipf("postgres not available: %v", err)
+	}
+
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// TableName returns a table name unique to the test, the tables named after
+// it are dropped with the test: the table itself and the table with every
+// suffix.
+func TableName(t testing.TB, pool *pgxpool.Pool, prefix string, suffixes ...string) string {
+	t.Helper()
+
+	table := fmt.Sprintf("%s_test_%d", prefix, time.Now().UnixNano())
+	t.Cleanup(func() {
+		for _, suffix := range append([]string{""}, suffixes...) {
+			_, err := pool.Exec(context.Background(), fmt.Sprintf("DROP TABLE IF EXISTS %s%s", table, suffix))
+			if err != nil {
+				t.Logf("drop test table %s%s: %v", table, suffix, err)
+			}
+		}
+	})
+	return table
+}
@@ -0,0 +1,164 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PublishOptions are the attributes and the ordering key of a published
+// message. The trace context is added to the attributes.
+type PublishOptions struct {
+	Attributes  map[string]string
+	OrderingKey string
+}
+
+// publishSettings returns the batching and flow control settings of the
+// topics from the PUBSUB_BATCH_* and PUBSUB_FLOW_* config, unset values keep
+// the pubsub defaults.
+func publishSettings(config *common_utils.BaseConfig) pubsub.PublishSettings {
+	settings := pubsub.DefaultPublishSettings
+
+	if config.PubsubBatchCount > 0 {
+		settings.CountThreshold = config.PubsubBatchCount
+	}
+	if config.PubsubBatchBytes > 0 {
+		settings.ByteThreshold = config.PubsubBatchBytes
+	}
+	if config.PubsubBatchDelay > 0 {
+		settings.DelayThreshold = config.PubsubBatchDelay
+	}
+	if config.PubsubFlowMaxMessages > 0 {
+		settings.FlowControlSettings.MaxOutstandingMessages = config.PubsubFlowMaxMessages
+	}
+	if config.PubsubFlowMaxBytes > 0 {
+		settings.FlowControlSettings.MaxOutstandingBytes = config.PubsubFlowMaxBytes
+	}
+	if config.PubsubFlowBlock {
+		settings.FlowControlSettings.LimitExceededBehavior = pubsub.FlowControlBlock
+	}
+
+	return settings
+}
+
+// topic returns the cached topic of name, creating it when it does not exist.
+// Reusing the topic lets the client batch the messages of concurrent
+// publishers.
+func (p *PubSubClientImpl) topic(ctx context.Context, name string) (*pubsub.Topic, error) {
+	p.mu.Lock()
+	topic, ok := p.topics[name]
+	p.mu.Unlock()
+	if ok {
+		return topic, nil
+	}
+
+	topic, err := p.CreateTopicIfNotExists(ctx, name)
+	if status.Code(err) == codes.AlreadyExists {
+		// a concurrent caller created it first
+		topic, err = p.pubSub.Topic(name), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	topic.EnableMessageOrdering = true
+	topic.PublishSettings = publishSettings(p.config)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// a concurrent caller cached the topic first, its handle is shared
+	if cached, ok := p.topics[name]; ok {
+		topic.Stop()
+		return cached, nil
+	}
+	p.topics[name] = topic
+	return topic, nil
+}
+
+// PublishToTopics publishes data to the topics, creating the missing ones,
+// and returns the message ID of every topic.
+func (p *PubSubClientImpl) PublishToTopics(ctx context.Context, topicsName []string, data any, opts PublishOptions) ([]string, error) {
+	if len(topicsName) == 0 {
+		return nil, nil
+	}
+
+	topics := make([]*pubsub.Topic, len(topicsName))
+	for i := range topicsName {
+		topic, err := p.topic(ctx, topicsName[i])
+		if err != nil {
+			common_utils.LogError(fmt.Sprintf("error when creating topic: [%s] \n", topicsName[i]))
+			return nil, err
+		}
+		topics[i] = topic
+	}
+
+	return p.PublishWithOptions(ctx, topics, data, opts)
+}
+
+// PublishWithOptions publishes data to every topic and returns the message
+// IDs in the order of the topics, the ID of a failed topic is empty. After a
+// failure publishing with an ordering key is resumed for that key, pubsub
+// pauses the key otherwise.
+func (p *PubSubClientImpl) PublishWithOptions(ctx context.Context, topics []*pubsub.Topic, data any, opts PublishOptions) ([]string, error) {
+	spanCtx, span := tracer.StartAndTraceWithData(ctx, "pubsub.Publish", opts.OrderingKey)
+	defer span.End()
+
+	message, err := common_utils.Marshal(data)
+	if err != nil {
+		return nil, tracer.TraceWithErr(spanCtx, err)
+	}
+
+	attributes := make(map[string]string, len(opts.Attributes))
+	for key, value := range opts.Attributes {
+		attributes[key] = value
+	}
+	attributes = tracer.InjectPubsubAttributes(spanCtx, attributes)
+
+	results := make([]*pubsub.PublishResult, 0, len(topics))
+	for _, topic := range topics {
+		results = append(results, topic.Publish(spanCtx, &pubsub.Message{
+			Data:        message,
+			Attributes:  attributes,
+			OrderingKey: opts.OrderingKey,
+		}))
+	}
+
+	ids := make([]string, len(topics))
+	var errs []error
+	for i, result := range results {
+		id, err := result.Get(spanCtx)
+		if err != nil {
+			if opts.OrderingKey != "" {
+				topics[i].ResumePublish(opts.OrderingKey)
+			}
+			errs = append(errs, fmt.Errorf("publish to %s: %w", topics[i].ID(), err))
+			continue
+		}
+		ids[i] = id
+		common_utils.LogInfo(fmt.Sprintf("publish message with ID: %s", id))
+	}
+
+	return ids, tracer.TraceWithErr(spanCtx, errors.Join(errs...))
+}
+
+// Publisher publishes values of T to a fixed set of topics.
+type Publisher[T any] struct {
+	client PubSubClient
+	topics []string
+}
+
+func NewPublisher[T any](client PubSubClient, topics ...string) *Publisher[T] {
+	return &Publisher[T]{client: client, topics: topics}
+}
+
+// Publish publishes data to the topics of the publisher and returns the
+// message ID of every topic.
+func (p *Publisher[T]) Publish(ctx context.Context, data T, opts PublishOptions) ([]string, error) {
+	return p.client.PublishToTopics(ctx, p.topics, data, opts)
+}
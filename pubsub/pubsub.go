@@ -9,8 +9,12 @@ import (
 type PubSubClient interface {
 	CreateTopicIfNotExists(ctx context.Context, topicName string) (*pubsub.Topic, error)
 	CreateSubscriptionIfNotExists(ctx context.Context, id string, topic *pubsub.Topic) (*pubsub.Subscription, error)
+	CreateSubscriptionWithOptions(ctx context.Context, id string, topic *pubsub.Topic, opts SubscriptionOptions) (*pubsub.Subscription, error)
 	PublishTopics(ctx context.Context, topics []*pubsub.Topic, data any, orderingKey string) error
+	PublishWithOptions(ctx context.Context, topics []*pubsub.Topic, data any, opts PublishOptions) ([]string, error)
+	PublishToTopics(ctx context.Context, topicsName []string, data any, opts PublishOptions) ([]string, error)
 	PullMessages(ctx context.Context, id string, topic *pubsub.Topic, callback func(ctx context.Context, msg *pubsub.Message)) error
+	PullMessagesWithOptions(ctx context.Context, id string, topic *pubsub.Topic, opts ReceiveOptions, callback func(ctx context.Context, msg *pubsub.Message)) error
 	Close() error
 	CheckTopicAndPublish(ctx context.Context, topicsName []string, orderingKey string, data any)
 }
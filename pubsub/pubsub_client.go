@@ -2,8 +2,7 @@ package pubsub
 
 import (
 	"context"
-	"fmt"
-	"time"
+	"sync"
 
 	"cloud.google.com/go/pubsub"
 	common_utils "github.com/dispenal/go-common/utils"
@@ -20,6 +19,12 @@ type GooglePubSub interface {
 type PubSubClientImpl struct {
 	config *common_utils.BaseConfig
 	pubSub GooglePubSub
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+	// subscriptions holds the IDs of the subscriptions known to exist, with
+	// whether exactly-once delivery was enabled.
+	subscriptions map[string]bool
 }
 
 func NewGooglePubSub(config *common_utils.BaseConfig) (c *pubsub.Client, err error) {
@@ -30,7 +35,12 @@ func NewGooglePubSub(config *common_utils.BaseConfig) (c *pubsub.Client, err err
 }
 
 func NewPubSubClient(config *common_utils.BaseConfig, pubSub GooglePubSub) PubSubClient {
-	return &PubSubClientImpl{config: config, pubSub: pubSub}
+	return &PubSubClientImpl{
+		config:        config,
+		pubSub:        pubSub,
+		topics:        make(map[string]*pubsub.Topic),
+		subscriptions: make(map[string]bool),
+	}
 }
 
 func (p *PubSubClientImpl) CreateTopicIfNotExists(ctx context.Context, topicName string) (*pubsub.Topic, error) {
@@ -46,9 +56,12 @@ func (p *PubSubClientImpl) CreateTopicIfNotExists(ctx context.Context, topicName
 	}
 
 	tpc, err = p.pubSub.CreateTopic(ctx, topicName)
+	if err != nil {
+		return nil, err
+	}
 	tpc.EnableMessageOrdering = true
 
-	return tpc, err
+	return tpc, nil
 }
 
 func (p *PubSubClientImpl) CreateSubscriptionIfNotExists(ctx context.Context, id string, topic *pubsub.Topic) (*pubsub.Subscription, error) {
@@ -63,63 +76,35 @@ func (p *PubSubClientImpl) CreateSubscriptionIfNotExists(ctx context.Context, id
 		return sub, nil
 	}
 
-	return p.pubSub.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{
-		Topic:                 topic,
-		EnableMessageOrdering: true,
-		AckDeadline:           30 * time.Second,
-		DeadLetterPolicy: &pubsub.DeadLetterPolicy{
-			DeadLetterTopic:     fmt.Sprintf("projects/%s/topics/%s", p.config.GcpProjectId, p.config.PubsubDlq),
-			MaxDeliveryAttempts: 5,
-		},
-	})
+	return p.pubSub.CreateSubscription(ctx, id, p.subscriptionConfig(topic, subscriptionOptions(p.config)))
 }
 
 func (p *PubSubClientImpl) PublishTopics(ctx context.Context, topics []*pubsub.Topic, data any, orderingKey string) error {
-	var results []*pubsub.PublishResult
-
-	message, err := common_utils.Marshal(data)
-	if err != nil {
-		return err
-	}
-
 	for _, topic := range topics {
 		topic.EnableMessageOrdering = true
-		res := topic.Publish(ctx, &pubsub.Message{
-			Data:        message,
-			OrderingKey: orderingKey,
-		})
-		results = append(results, res)
-	}
-
-	for _, result := range results {
-		id, err := result.Get(ctx)
-		if err != nil {
-			return err
-		}
-		common_utils.LogInfo(fmt.Sprintf("publish message with ID: %s", id))
 	}
 
-	return nil
+	_, err := p.PublishWithOptions(ctx, topics, data, PublishOptions{OrderingKey: orderingKey})
+	return err
 }
 
+// PullMessages receives the messages of the subscription id with the
+// PUBSUB_SUB_* settings until ctx is done.
 func (p *PubSubClientImpl) PullMessages(ctx context.Context, id string, topic *pubsub.Topic, callback func(ctx context.Context, msg *pubsub.Message)) error {
-	defer p.pubSub.Close()
-
-	sub, err := p.CreateSubscriptionIfNotExists(ctx, id, topic)
-	if err != nil {
-		return err
-	}
-
-	// sub.ReceiveSettings.Synchronous = true
-	// sub.ReceiveSettings.MaxOutstandingMessages = 30
-	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		common_utils.LogInfo(fmt.Sprintf("received message with messageID: %s, topicID: %s, ordering key: %s \n", msg.ID, topic.ID(), msg.OrderingKey))
-
-		callback(ctx, msg)
-	})
+	return p.PullMessagesWithOptions(ctx, id, topic, receiveOptions(p.config), callback)
 }
 
+// Close flushes and stops the topics cached by PublishToTopics before closing
+// the client.
 func (p *PubSubClientImpl) Close() error {
+	p.mu.Lock()
+	for name, topic := range p.topics {
+		topic.Stop()
+		delete(p.topics, name)
+	}
+	clear(p.subscriptions)
+	p.mu.Unlock()
+
 	return p.pubSub.Close()
 }
 
@@ -129,18 +114,6 @@ func (p *PubSubClientImpl) CheckTopicAndPublish(
 	orderingKey string,
 	data any,
 ) {
-	if len(topicsName) == 0 {
-		return
-	}
-	topics := make([]*pubsub.Topic, len(topicsName))
-	for i := range topicsName {
-		topic, err := p.CreateTopicIfNotExists(ctx, topicsName[i])
-		if err != nil {
-			common_utils.LogError(fmt.Sprintf("error when creating topic: [%s] \n", topicsName[i]))
-			return
-		}
-		topics[i] = topic
-	}
-	err := p.PublishTopics(ctx, topics, data, orderingKey)
+	_, err := p.PublishToTopics(ctx, topicsName, data, PublishOptions{OrderingKey: orderingKey})
 	common_utils.LogIfError(err)
 }
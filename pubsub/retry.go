@@ -0,0 +1,113 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	common_utils "github.com/dispenal/go-common/utils"
+)
+
+// SubscriptionOptions are the delivery settings of a created subscription.
+// Nacked messages are redelivered with an exponential backoff between
+// MinBackoff and MaxBackoff, after MaxDeliveryAttempts they are forwarded to
+// DeadLetterTopic. Without a DeadLetterTopic messages are redelivered until
+// they are acked and msg.DeliveryAttempt is nil.
+type SubscriptionOptions struct {
+	AckDeadline         time.Duration
+	MinBackoff          time.Duration
+	MaxBackoff          time.Duration
+	MaxDeliveryAttempts int
+	DeadLetterTopic     string
+	ExactlyOnce         bool
+}
+
+// subscriptionOptions returns the SubscriptionOptions of the PUBSUB_* config.
+func subscriptionOptions(config *common_utils.BaseConfig) SubscriptionOptions {
+	return SubscriptionOptions{
+		AckDeadline:         config.PubsubAckDeadline,
+		MinBackoff:          config.PubsubMinBackoff,
+		MaxBackoff:          config.PubsubMaxBackoff,
+		MaxDeliveryAttempts: config.PubsubMaxAttempts,
+		DeadLetterTopic:     config.PubsubDlq,
+		ExactlyOnce:         config.PubsubExactlyOnce,
+	}
+}
+
+func (p *PubSubClientImpl) subscriptionConfig(topic *pubsub.Topic, opts SubscriptionOptions) pubsub.SubscriptionConfig {
+	cfg := pubsub.SubscriptionConfig{
+		Topic:                     topic,
+		EnableMessageOrdering:     true,
+		EnableExactlyOnceDelivery: opts.ExactlyOnce,
+		AckDeadline:               opts.AckDeadline,
+	}
+	if cfg.AckDeadline == 0 {
+		cfg.AckDeadline = 30 * time.Second
+	}
+
+	if opts.MinBackoff > 0 || opts.MaxBackoff > 0 {
+		cfg.RetryPolicy = &pubsub.RetryPolicy{}
+		if opts.MinBackoff > 0 {
+			cfg.RetryPolicy.MinimumBackoff = opts.MinBackoff
+		}
+		if opts.MaxBackoff > 0 {
+			cfg.RetryPolicy.MaximumBackoff = opts.MaxBackoff
+		}
+	}
+
+	if opts.DeadLetterTopic != "" {
+		maxAttempts := opts.MaxDeliveryAttempts
+		if maxAttempts == 0 {
+			maxAttempts = 5
+		}
+		cfg.DeadLetterPolicy = &pubsub.DeadLetterPolicy{
+			DeadLetterTopic:     fmt.Sprintf("projects/%s/topics/%s", p.config.GcpProjectId, opts.DeadLetterTopic),
+			MaxDeliveryAttempts: maxAttempts,
+		}
+	}
+
+	return cfg
+}
+
+// CreateSubscriptionWithOptions creates the subscription id when it does not
+// exist. An existing subscription keeps its settings, PullMessages then skips
+// the existence check.
+func (p *PubSubClientImpl) CreateSubscriptionWithOptions(ctx context.Context, id string, topic *pubsub.Topic, opts SubscriptionOptions) (*pubsub.Subscription, error) {
+	return p.ensureSubscription(ctx, id, topic, opts)
+}
+
+// FailureFunc is called with the last error of a message whose delivery
+// attempts are exhausted. The message is acked when it returns nil, and
+// nacked otherwise so that it is forwarded to the dead letter topic.
+type FailureFunc func(ctx context.Context, msg *pubsub.Message, err error) error
+
+// RetryHandler wraps f into a PullMessages callback. The message is acked when
+// f succeeds and nacked when it fails, the redelivery is delayed by the retry
+// policy of the subscription instead of blocking the callback. Once
+// maxDeliveryAttempts is reached onFailure decides what happens to the
+// message.
+func RetryHandler(maxDeliveryAttempts int, f func(ctx context.Context, msg *pubsub.Message) error, onFailure FailureFunc) func(ctx context.Context, msg *pubsub.Message) {
+	return func(ctx context.Context, msg *pubsub.Message) {
+		err := f(ctx, msg)
+		if err == nil {
+			msg.Ack()
+			return
+		}
+
+		if onFailure == nil || msg.DeliveryAttempt == nil || *msg.DeliveryAttempt < maxDeliveryAttempts {
+			common_utils.LogError(fmt.Sprintf("retry message with messageID: %s, orderingKey: %s: %v", msg.ID, msg.OrderingKey, err))
+			msg.Nack()
+			return
+		}
+
+		if err := onFailure(ctx, msg, err); err != nil {
+			common_utils.LogError(fmt.Sprintf("failed handle exhausted message with messageID: %s: %v", msg.ID, err))
+			msg.Nack()
+			return
+		}
+
+		common_utils.LogInfo(fmt.Sprintf("acknowledged exhausted message with messageID: %s", msg.ID))
+		msg.Ack()
+	}
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryHandler(t *testing.T) {
+	ctx := context.Background()
+	p := newTestClient(t)
+
+	_, err := p.CreateTopicIfNotExists(ctx, "dlq")
+	require.NoError(t, err)
+	topic, err := p.topic(ctx, "payments")
+	require.NoError(t, err)
+
+	opts := SubscriptionOptions{MinBackoff: time.Millisecond, MaxDeliveryAttempts: 5, DeadLetterTopic: "dlq"}
+	_, err = p.CreateSubscriptionWithOptions(ctx, "payments-sub", topic, opts)
+	require.NoError(t, err)
+
+	failed := make(chan int, 1)
+	attempts := 0
+	handler := RetryHandler(opts.MaxDeliveryAttempts, func(ctx context.Context, msg *pubsub.Message) error {
+		attempts++
+		return errors.New("payment provider unavailable")
+	}, func(ctx context.Context, msg *pubsub.Message, err error) error {
+		failed <- *msg.DeliveryAttempt
+		return nil
+	})
+
+	sub := NewSubscriber(p, "payments-sub", topic, ReceiveOptions{NumGoroutines: 1, MaxOutstandingMessages: 1}, handler)
+	sub.Start(ctx)
+	defer sub.Stop(ctx)
+
+	_, err = p.PublishToTopics(ctx, []string{"payments"}, map[string]string{"id": "1"}, PublishOptions{})
+	require.NoError(t, err)
+
+	select {
+	case attempt := <-failed:
+		require.Equal(t, 5, attempt)
+	case <-time.After(10 * time.Second):
+		t.Fatal("failure callback not called")
+	}
+	require.NoError(t, sub.Stop(ctx))
+	require.Equal(t, 5, attempts)
+}
+
+func TestSubscriptionConfig(t *testing.T) {
+	p := newTestClient(t)
+
+	cfg := p.subscriptionConfig(nil, SubscriptionOptions{})
+	require.Equal(t, 30*time.Second, cfg.AckDeadline)
+	require.Nil(t, cfg.RetryPolicy)
+	require.Nil(t, cfg.DeadLetterPolicy)
+
+	cfg = p.subscriptionConfig(nil, SubscriptionOptions{MinBackoff: time.Second, DeadLetterTopic: "dlq", MaxDeliveryAttempts: 10})
+	require.Equal(t, time.Second, cfg.RetryPolicy.MinimumBackoff)
+	require.Nil(t, cfg.RetryPolicy.MaximumBackoff)
+	require.Equal(t, "projects/test/topics/dlq", cfg.DeadLetterPolicy.DeadLetterTopic)
+	require.Equal(t, 10, cfg.DeadLetterPolicy.MaxDeliveryAttempts)
+}
@@ -0,0 +1,219 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/dispenal/go-common/tracer"
+	common_utils "github.com/dispenal/go-common/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReceiveOptions are the flow control and concurrency settings of a
+// subscriber. Zero values keep the pubsub defaults.
+//
+// With ExactlyOnce the subscription is created, or updated, with exactly-once
+// delivery, handlers should then ack with msg.AckWithResult to know whether
+// the ack was persisted.
+type ReceiveOptions struct {
+	MaxOutstandingMessages int
+	MaxOutstandingBytes    int
+	NumGoroutines          int
+	MaxExtension           time.Duration
+	Synchronous            bool
+	ExactlyOnce            bool
+}
+
+// receiveOptions returns the ReceiveOptions of the PUBSUB_SUB_* config.
+func receiveOptions(config *common_utils.BaseConfig) ReceiveOptions {
+	return ReceiveOptions{
+		MaxOutstandingMessages: config.PubsubSubMaxMessages,
+		MaxOutstandingBytes:    config.PubsubSubMaxBytes,
+		NumGoroutines:          config.PubsubSubGoroutines,
+		MaxExtension:           config.PubsubSubMaxExtension,
+		Synchronous:            config.PubsubSubSynchronous,
+		ExactlyOnce:            config.PubsubExactlyOnce,
+	}
+}
+
+func receiveSettings(opts ReceiveOptions) pubsub.ReceiveSettings {
+	settings := pubsub.DefaultReceiveSettings
+
+	if opts.MaxOutstandingMessages != 0 {
+		settings.MaxOutstandingMessages = opts.MaxOutstandingMessages
+	}
+	if opts.MaxOutstandingBytes != 0 {
+		settings.MaxOutstandingBytes = opts.MaxOutstandingBytes
+	}
+	if opts.NumGoroutines > 0 {
+		settings.NumGoroutines = opts.NumGoroutines
+	}
+	if opts.MaxExtension != 0 {
+		settings.MaxExtension = opts.MaxExtension
+	}
+	settings.Synchronous = opts.Synchronous
+
+	return settings
+}
+
+// ensureSubscription returns a new handle of the subscription id, creating it
+// with opts when it does not exist. Only the outcome of the existence check is
+// cached, every caller gets its own handle to receive with.
+func (p *PubSubClientImpl) ensureSubscription(ctx context.Context, id string, topic *pubsub.Topic, opts SubscriptionOptions) (*pubsub.Subscription, error) {
+	sub := p.pubSub.Subscription(id)
+
+	p.mu.Lock()
+	exactlyOnce, ensured := p.subscriptions[id]
+	p.mu.Unlock()
+	if ensured && (exactlyOnce || !opts.ExactlyOnce) {
+		return sub, nil
+	}
+
+	ok, err := sub.Exists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		_, err = p.pubSub.CreateSubscription(ctx, id, p.subscriptionConfig(topic, opts))
+		// a concurrent caller created it first
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return nil, err
+		}
+	}
+	if opts.ExactlyOnce {
+		if err := enableExactlyOnce(ctx, sub); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	p.subscriptions[id] = p.subscriptions[id] || opts.ExactlyOnce
+	p.mu.Unlock()
+
+	return sub, nil
+}
+
+func enableExactlyOnce(ctx context.Context, sub *pubsub.Subscription) error {
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cfg.EnableExactlyOnceDelivery {
+		return nil
+	}
+
+	_, err = sub.Update(ctx, pubsub.SubscriptionConfigToUpdate{EnableExactlyOnceDelivery: true})
+	return err
+}
+
+// PullMessagesWithOptions receives the messages of the subscription id until
+// ctx is done, a missing subscription is created with the PUBSUB_* settings.
+// The handler ctx carries the trace context of the publisher. The client
+// stays open, cancel ctx to stop receiving.
+func (p *PubSubClientImpl) PullMessagesWithOptions(
+	ctx context.Context,
+	id string,
+	topic *pubsub.Topic,
+	opts ReceiveOptions,
+	callback func(ctx context.Context, msg *pubsub.Message),
+) error {
+	subOpts := subscriptionOptions(p.config)
+	subOpts.ExactlyOnce = opts.ExactlyOnce
+
+	sub, err := p.ensureSubscription(ctx, id, topic, subOpts)
+	if err != nil {
+		return err
+	}
+
+	sub.ReceiveSettings = receiveSettings(opts)
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		common_utils.LogInfo(fmt.Sprintf("received message with messageID: %s, topicID: %s, ordering key: %s \n", msg.ID, topic.ID(), msg.OrderingKey))
+
+		spanCtx, span := tracer.StartAndTracePubsub(ctx, "pubsub.Receive", msg)
+		defer span.End()
+
+		callback(spanCtx, msg)
+	})
+}
+
+// Subscriber receives the messages of a subscription in the background until
+// it is stopped.
+type Subscriber struct {
+	client   PubSubClient
+	id       string
+	topic    *pubsub.Topic
+	opts     ReceiveOptions
+	callback func(ctx context.Context, msg *pubsub.Message)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func NewSubscriber(
+	client PubSubClient,
+	id string,
+	topic *pubsub.Topic,
+	opts ReceiveOptions,
+	callback func(ctx context.Context, msg *pubsub.Message),
+) *Subscriber {
+	return &Subscriber{client: client, id: id, topic: topic, opts: opts, callback: callback}
+}
+
+// Start starts receiving, it returns immediately. Starting a running
+// subscriber does nothing.
+func (s *Subscriber) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	s.err = nil
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		err := s.client.PullMessagesWithOptions(ctx, s.id, s.topic, s.opts, s.callback)
+		common_utils.LogIfError(err)
+
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}(s.done)
+}
+
+// Stop stops receiving and waits for the running handlers to return, or for
+// ctx to be done. It returns the error that stopped the receiving, if any.
+func (s *Subscriber) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancel, s.done = nil, nil
+	return s.err
+}
@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newTestClient(t *testing.T) *PubSubClientImpl {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.Dial(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	client, err := pubsub.NewClient(context.Background(), "test", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+
+	config := &common_utils.BaseConfig{GcpProjectId: "test", PubsubDlq: "dlq", PubsubBatchDelay: time.Millisecond}
+	p := NewPubSubClient(config, client).(*PubSubClientImpl)
+	t.Cleanup(func() { p.Close() })
+
+	return p
+}
+
+func TestSubscriber(t *testing.T) {
+	ctx := context.Background()
+	p := newTestClient(t)
+
+	_, err := p.CreateTopicIfNotExists(ctx, "dlq")
+	require.NoError(t, err)
+	topic, err := p.topic(ctx, "orders")
+	require.NoError(t, err)
+
+	received := make(chan *pubsub.Message, 1)
+	sub := NewSubscriber(p, "orders-sub", topic, ReceiveOptions{NumGoroutines: 1, MaxOutstandingMessages: 1}, func(ctx context.Context, msg *pubsub.Message) {
+		msg.Ack()
+		received <- msg
+	})
+	sub.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		_, ok := p.subscriptions["orders-sub"]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	ids, err := p.PublishToTopics(ctx, []string{"orders"}, map[string]string{"id": "1"}, PublishOptions{
+		Attributes:  map[string]string{"type": "created"},
+		OrderingKey: "1",
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	select {
+	case msg := <-received:
+		require.Equal(t, ids[0], msg.ID)
+		require.Equal(t, "created", msg.Attributes["type"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	require.NoError(t, sub.Stop(ctx))
+	require.NoError(t, sub.Stop(ctx))
+
+	// the client stays usable after the subscriber stopped
+	_, err = p.PublishToTopics(ctx, []string{"orders"}, map[string]string{"id": "2"}, PublishOptions{})
+	require.NoError(t, err)
+}
+
+func TestPullMessagesConcurrently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newTestClient(t)
+
+	_, err := p.CreateTopicIfNotExists(ctx, "dlq")
+	require.NoError(t, err)
+	topic, err := p.topic(ctx, "orders")
+	require.NoError(t, err)
+
+	// every pull receives with its own subscription handle, a shared handle
+	// rejects a second Receive
+	errs := make(chan error, 2)
+	for _, opts := range []ReceiveOptions{{NumGoroutines: 1}, {NumGoroutines: 2, Synchronous: true}} {
+		go func(opts ReceiveOptions) {
+			errs <- p.PullMessagesWithOptions(ctx, "orders-sub", topic, opts, func(ctx context.Context, msg *pubsub.Message) {
+				msg.Ack()
+			})
+		}(opts)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("pull stopped before cancel: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	cancel()
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	require.Contains(t, p.subscriptions, "orders-sub")
+}
+
+func TestReceiveSettings(t *testing.T) {
+	settings := receiveSettings(ReceiveOptions{})
+	require.Equal(t, pubsub.DefaultReceiveSettings, settings)
+
+	settings = receiveSettings(ReceiveOptions{MaxOutstandingMessages: 10, NumGoroutines: 2, MaxExtension: time.Minute, Synchronous: true})
+	require.Equal(t, 10, settings.MaxOutstandingMessages)
+	require.Equal(t, 2, settings.NumGoroutines)
+	require.Equal(t, time.Minute, settings.MaxExtension)
+	require.True(t, settings.Synchronous)
+}
@@ -8,6 +8,8 @@ import (
 	common_utils "github.com/dispenal/go-common/utils"
 )
 
+// Deprecated: SetRetryOrSetDataToDB blocks the receive callback while it
+// waits, use RetryHandler with the retry policy of the subscription instead.
 func SetRetryOrSetDataToDB(config *common_utils.BaseConfig, msg *pubsub.Message, cb func()) {
 	if msg.DeliveryAttempt != nil && *msg.DeliveryAttempt <= 4 {
 		time.Sleep(5 * time.Second)
@@ -0,0 +1,198 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis answers the commands of a redis client from memory, the client
+// never dials. Expirations are recorded but keys do not expire, only the lock
+// scripts are supported.
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+	ttls map[string]time.Duration
+}
+
+func newFakeRedis() *redis.Client {
+	client := redis.NewClient(&redis.Options{Addr: "fake:6379"})
+	client.AddHook(&fakeRedis{data: make(map[string]string), ttls: make(map[string]time.Duration)})
+	return client
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("fakeRedis: dial not supported")
+	}
+}
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.process(cmd)
+		return cmd.Err()
+	}
+}
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			f.process(cmd)
+		}
+		for _, cmd := range cmds {
+			if err := cmd.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (f *fakeRedis) process(cmd redis.Cmder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	args := make([]string, len(cmd.Args()))
+	for i, arg := range cmd.Args() {
+		switch v := arg.(type) {
+		case []byte:
+			args[i] = string(v)
+		default:
+			args[i] = fmt.Sprint(v)
+		}
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "get":
+		value, ok := f.data[args[1]]
+		if !ok {
+			cmd.SetErr(redis.Nil)
+			return
+		}
+		cmd.(*redis.StringCmd).SetVal(value)
+	case "set":
+		f.set(cmd, args)
+	case "del", "unlink":
+		var deleted int64
+		for _, key := range args[1:] {
+			if _, ok := f.data[key]; ok {
+				delete(f.data, key)
+				delete(f.ttls, key)
+				deleted++
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(deleted)
+	case "ttl":
+		ttlCmd := cmd.(*redis.DurationCmd)
+		if _, ok := f.data[args[1]]; !ok {
+			ttlCmd.SetVal(-2)
+		} else if ttl := f.ttls[args[1]]; ttl > 0 {
+			ttlCmd.SetVal(ttl)
+		} else {
+			ttlCmd.SetVal(-1)
+		}
+	case "scan":
+		cmd.(*redis.ScanCmd).SetVal(f.keys(args[3]), 0)
+	case "keys":
+		cmd.(*redis.StringSliceCmd).SetVal(f.keys(args[1]))
+	case "evalsha":
+		cmd.SetErr(fakeRedisError("NOSCRIPT No matching script"))
+	case "eval":
+		f.eval(cmd.(*redis.Cmd), args)
+	default:
+		cmd.SetErr(fmt.Errorf("fakeRedis: %s not supported", args[0]))
+	}
+}
+
+// set handles SET key value [EX seconds | PX milliseconds] [NX].
+func (f *fakeRedis) set(cmd redis.Cmder, args []string) {
+	key, value := args[1], args[2]
+
+	var ttl time.Duration
+	nx := false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "ex":
+			var seconds int64
+			fmt.Sscan(args[i+1], &seconds)
+			ttl = time.Duration(seconds) * time.Second
+			i++
+		case "px":
+			var millis int64
+			fmt.Sscan(args[i+1], &millis)
+			ttl = time.Duration(millis) * time.Millisecond
+			i++
+		case "nx":
+			nx = true
+		}
+	}
+
+	if _, ok := f.data[key]; ok && nx {
+		cmd.(*redis.BoolCmd).SetVal(false)
+		return
+	}
+
+	f.data[key] = value
+	f.ttls[key] = ttl
+	switch c := cmd.(type) {
+	case *redis.BoolCmd:
+		c.SetVal(true)
+	case *redis.StatusCmd:
+		c.SetVal("OK")
+	}
+}
+
+func (f *fakeRedis) keys(pattern string) []string {
+	var keys []string
+	for key := range f.data {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type fakeRedisError string
+
+func (e fakeRedisError) Error() string { return string(e) }
+
+func (e fakeRedisError) RedisError() {}
+
+// eval runs the lock scripts, EVAL script numkeys key [key ...] arg [arg ...].
+func (f *fakeRedis) eval(cmd *redis.Cmd, args []string) {
+	var numKeys int
+	fmt.Sscan(args[2], &numKeys)
+	keys, argv := args[3:3+numKeys], args[3+numKeys:]
+
+	holds := f.data[keys[0]] == argv[0]
+	switch args[1] {
+	case unlockScriptSrc:
+		if !holds {
+			cmd.SetVal(int64(0))
+			return
+		}
+		delete(f.data, keys[0])
+		delete(f.ttls, keys[0])
+		cmd.SetVal(int64(1))
+	case extendScriptSrc:
+		if !holds {
+			cmd.SetVal(int64(0))
+			return
+		}
+		var millis int64
+		fmt.Sscan(argv[1], &millis)
+		f.ttls[keys[0]] = time.Duration(millis) * time.Millisecond
+		cmd.SetVal(int64(1))
+	default:
+		cmd.SetErr(errors.New("fakeRedis: script not supported"))
+	}
+}
@@ -0,0 +1,109 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+)
+
+// LeaderCallbacks are called by a LeaderElector. OnStartedLeading runs while
+// the instance is the leader, its ctx is cancelled when the leadership is
+// lost and returning from it gives the leadership up. OnStoppedLeading is
+// called after OnStartedLeading returned.
+type LeaderCallbacks struct {
+	OnStartedLeading func(ctx context.Context)
+	OnStoppedLeading func()
+}
+
+// LeaderElector elects a single leader among the instances running it with
+// the same key, the leader holds the lock key.
+type LeaderElector struct {
+	locker      *Locker
+	key         string
+	ttl         time.Duration
+	retryPeriod time.Duration
+	callbacks   LeaderCallbacks
+	leader      atomic.Bool
+}
+
+// NewLeaderElector returns an elector holding the lock key for ttl, the
+// followers try to take it every ttl/2. A ttl shorter than MinLockTTL is
+// rejected with ErrInvalidLockTTL.
+func NewLeaderElector(locker *Locker, key string, ttl time.Duration, callbacks LeaderCallbacks) (*LeaderElector, error) {
+	if err := validateLockTTL(ttl); err != nil {
+		return nil, err
+	}
+
+	return &LeaderElector{
+		locker:      locker,
+		key:         key,
+		ttl:         ttl,
+		retryPeriod: ttl / 2,
+		callbacks:   callbacks,
+	}, nil
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run takes part in the election until ctx is done, the leadership is then
+// released for the other instances.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	for {
+		lock, err := e.locker.TryLock(ctx, e.key, e.ttl)
+		switch {
+		case err == nil:
+			e.lead(ctx, lock)
+		case !errors.Is(err, ErrLockNotAcquired):
+			common_utils.LogError(fmt.Sprintf("failed acquire leader lock %s: %v", e.key, err))
+		}
+
+		timer := time.NewTimer(e.retryPeriod)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *LeaderElector) lead(ctx context.Context, lock *Lock) {
+	common_utils.LogInfo(fmt.Sprintf("started leading: %s", e.key))
+	e.leader.Store(true)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if e.callbacks.OnStartedLeading != nil {
+			e.callbacks.OnStartedLeading(leaderCtx)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-lock.Lost():
+	case <-done:
+	}
+	cancel()
+	<-done
+
+	// the lock is released once the leader work stopped, so that the next
+	// leader does not overlap with it
+	err := lock.Release(context.WithoutCancel(ctx))
+	if err != nil && !errors.Is(err, ErrLockNotHeld) {
+		common_utils.LogError(fmt.Sprintf("failed release leader lock %s: %v", e.key, err))
+	}
+
+	e.leader.Store(false)
+	common_utils.LogInfo(fmt.Sprintf("stopped leading: %s", e.key))
+	if e.callbacks.OnStoppedLeading != nil {
+		e.callbacks.OnStoppedLeading()
+	}
+}
@@ -0,0 +1,191 @@
+package redis_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound is returned by a GetOrLoad loader when the value does not
+// exist, the result is cached for LoadOptions.NotFoundTTL.
+var ErrNotFound = errors.New("redis_client: not found")
+
+// notFound is the cached value of a not found result.
+var notFound = []byte(`{"__not_found":true}`)
+
+// loadGroupKey separates the loads of the cache services and value types, a
+// load is only shared by the callers expecting the same type.
+type loadGroupKey struct {
+	svc CacheSvc
+	typ reflect.Type
+}
+
+// loadGroups holds the *singleflight.Group of every loadGroupKey.
+var loadGroups sync.Map
+
+func loadGroup[T any](svc CacheSvc) *singleflight.Group {
+	key := loadGroupKey{svc: svc, typ: reflect.TypeOf((*T)(nil)).Elem()}
+	group, _ := loadGroups.LoadOrStore(key, &singleflight.Group{})
+	return group.(*singleflight.Group)
+}
+
+// LoadOptions tunes GetOrLoad.
+//
+// NotFoundTTL enables the negative caching of ErrNotFound. With Lock a Redis
+// lock on "<key>:lock" lets a single instance run the loader, the others wait
+// up to LockWait for the value before loading it themselves.
+type LoadOptions struct {
+	NotFoundTTL time.Duration
+	Lock        bool
+	LockTTL     time.Duration
+	LockWait    time.Duration
+}
+
+const (
+	defaultLoadLockTTL  = 10 * time.Second
+	defaultLoadLockWait = 5 * time.Second
+	loadLockPoll        = 50 * time.Millisecond
+)
+
+// GetOrLoad returns the cached value of key, or loads it with loader and
+// caches it for ttl. Concurrent misses of a key in the process share a single
+// loader call. Loader errors are returned and not cached.
+func GetOrLoad[T any](ctx context.Context, svc CacheSvc, key string, loader func(ctx context.Context) (T, error), ttl time.Duration, opts ...LoadOptions) (T, error) {
+	var opt LoadOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if value, ok, err := getCached[T](ctx, svc, key); ok || err != nil {
+		return value, err
+	}
+
+	result := loadGroup[T](svc).DoChan(key, func() (any, error) {
+		// the load is shared, a caller giving up must not cancel it for the
+		// others
+		ctx := context.WithoutCancel(ctx)
+		if !opt.Lock {
+			return loadAndSet(ctx, svc, key, loader, ttl, opt)
+		}
+		return loadWithLock(ctx, svc, key, loader, ttl, opt)
+	})
+
+	select {
+	case res := <-result:
+		var zero T
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		if res.Val == nil {
+			// a nil interface value, which fails the type assertion
+			return zero, nil
+		}
+		value, ok := res.Val.(T)
+		if !ok {
+			return zero, fmt.Errorf("redis_client: loaded %T for key %s, expected %T", res.Val, key, zero)
+		}
+		return value, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// getCached returns the cached value of key. A missing or undecodable value is
+// a miss, a cached not found result returns ErrNotFound.
+func getCached[T any](ctx context.Context, svc CacheSvc, key string) (T, bool, error) {
+	var zero T
+
+	var raw json.RawMessage
+	if err := svc.Get(ctx, key, &raw); err != nil {
+		if !errors.Is(err, redis.Nil) {
+			common_utils.LogError(fmt.Sprintf("failed get cache key %s, loading it: %v", key, err))
+		}
+		return zero, false, nil
+	}
+
+	if string(raw) == string(notFound) {
+		return zero, true, ErrNotFound
+	}
+
+	var value T
+	if err := common_utils.Unmarshal(raw, &value); err != nil {
+		common_utils.LogError(fmt.Sprintf("failed unmarshal cache key %s, loading it: %v", key, err))
+		return zero, false, nil
+	}
+
+	return value, true, nil
+}
+
+func loadAndSet[T any](ctx context.Context, svc CacheSvc, key string, loader func(ctx context.Context) (T, error), ttl time.Duration, opt LoadOptions) (any, error) {
+	value, err := loader(ctx)
+	if errors.Is(err, ErrNotFound) && opt.NotFoundTTL > 0 {
+		common_utils.LogIfError(svc.Set(ctx, key, json.RawMessage(notFound), opt.NotFoundTTL))
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	common_utils.LogIfError(svc.Set(ctx, key, value, ttl))
+	return value, nil
+}
+
+func loadWithLock[T any](ctx context.Context, svc CacheSvc, key string, loader func(ctx context.Context) (T, error), ttl time.Duration, opt LoadOptions) (any, error) {
+	lockTTL := opt.LockTTL
+	if lockTTL == 0 {
+		lockTTL = defaultLoadLockTTL
+	}
+	lockWait := opt.LockWait
+	if lockWait == 0 {
+		lockWait = defaultLoadLockWait
+	}
+
+	lockKey := key + ":lock"
+	token := uuid.NewString()
+
+	locked, err := svc.SetNX(ctx, lockKey, token, lockTTL)
+	if err != nil {
+		common_utils.LogError(fmt.Sprintf("failed lock cache key %s, loading it: %v", key, err))
+	}
+	if locked {
+		defer func() {
+			// the lock may have expired and been taken by another instance
+			_, err := svc.DelIfEqual(context.WithoutCancel(ctx), lockKey, token)
+			common_utils.LogIfError(err)
+		}()
+		return loadAndSet(ctx, svc, key, loader, ttl, opt)
+	}
+
+	// another instance is loading the value, wait for it
+	timer := time.NewTimer(lockWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(loadLockPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return loadAndSet(ctx, svc, key, loader, ttl, opt)
+		case <-ticker.C:
+			value, ok, err := getCached[T](ctx, svc, key)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return value, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	cacheSvc := NewCacheSvc(&common_utils.BaseConfig{RedisCacheExpire: 60}, newFakeRedis())
+
+	t.Run("Load once for concurrent misses and keep the type", func(t *testing.T) {
+		data := testData{ID: uuid.New(), CreatedAt: time.Now()}
+
+		var calls atomic.Int32
+		release := make(chan struct{})
+		loader := func(ctx context.Context) (testData, error) {
+			calls.Add(1)
+			<-release
+			return data, nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([]testData, 10)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				output, err := GetOrLoad(ctx, cacheSvc, "load-struct", loader, time.Minute)
+				assert.NoError(t, err)
+				results[i] = output
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+		for _, output := range results {
+			assert.Equal(t, data.ID, output.ID)
+		}
+
+		output, err := GetOrLoad(ctx, cacheSvc, "load-struct", loader, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, data.ID, output.ID)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Return loader errors without caching them", func(t *testing.T) {
+		loadErr := errors.New("database unavailable")
+		_, err := GetOrLoad(ctx, cacheSvc, "load-error", func(ctx context.Context) (int, error) {
+			return 0, loadErr
+		}, time.Minute)
+		assert.ErrorIs(t, err, loadErr)
+
+		output, err := GetOrLoad(ctx, cacheSvc, "load-error", func(ctx context.Context) (int, error) {
+			return 42, nil
+		}, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 42, output)
+	})
+
+	t.Run("Cache not found results with NotFoundTTL", func(t *testing.T) {
+		var calls atomic.Int32
+		loader := func(ctx context.Context) (testData, error) {
+			calls.Add(1)
+			return testData{}, ErrNotFound
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := GetOrLoad(ctx, cacheSvc, "load-not-found", loader, time.Minute, LoadOptions{NotFoundTTL: time.Minute})
+			assert.ErrorIs(t, err, ErrNotFound)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Wait for the instance holding the lock", func(t *testing.T) {
+		locked, err := cacheSvc.SetNX(ctx, "load-locked:lock", "other-instance", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, locked)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			cacheSvc.Set(ctx, "load-locked", "from other instance", time.Minute)
+		}()
+
+		output, err := GetOrLoad(ctx, cacheSvc, "load-locked", func(ctx context.Context) (string, error) {
+			return "from this instance", nil
+		}, time.Minute, LoadOptions{Lock: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "from other instance", output)
+	})
+	t.Run("Share loads only between callers of the same type", func(t *testing.T) {
+		release := make(chan struct{})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			output, err := GetOrLoad(ctx, cacheSvc, "load-typed", func(ctx context.Context) (int, error) {
+				<-release
+				return 42, nil
+			}, time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, output)
+		}()
+		go func() {
+			defer wg.Done()
+			output, err := GetOrLoad(ctx, cacheSvc, "load-typed", func(ctx context.Context) (string, error) {
+				<-release
+				return "42", nil
+			}, time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, "42", output)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("Return a nil interface value", func(t *testing.T) {
+		output, err := GetOrLoad(ctx, cacheSvc, "load-nil", func(ctx context.Context) (any, error) {
+			return nil, nil
+		}, time.Minute)
+		assert.NoError(t, err)
+		assert.Nil(t, output)
+	})
+
+	t.Run("Keep a lock taken over by another instance", func(t *testing.T) {
+		output, err := GetOrLoad(ctx, cacheSvc, "load-expired", func(ctx context.Context) (string, error) {
+			// the lock expired during the load and another instance took it
+			assert.NoError(t, cacheSvc.Set(ctx, "load-expired:lock", "other-instance", time.Minute))
+			return "loaded", nil
+		}, time.Minute, LoadOptions{Lock: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "loaded", output)
+
+		var token string
+		assert.NoError(t, cacheSvc.Get(ctx, "load-expired:lock", &token))
+		assert.Equal(t, "other-instance", token)
+	})
+}
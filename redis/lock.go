@@ -0,0 +1,266 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrLockNotAcquired = errors.New("redis_client: lock not acquired")
+	ErrLockNotHeld     = errors.New("redis_client: lock not held")
+	ErrInvalidLockTTL  = errors.New("redis_client: invalid lock ttl")
+)
+
+const (
+	defaultLockMinBackoff = 50 * time.Millisecond
+	defaultLockMaxBackoff = time.Second
+)
+
+const (
+	// lockExtendDivisor is the number of automatic extensions per TTL, a
+	// holder has two more extend periods to reach redis before its lock
+	// expires.
+	lockExtendDivisor = 3
+	// minLockExtendPeriod is the shortest extend period: redis takes the TTL
+	// in whole milliseconds, a period below it extends by a truncated TTL.
+	minLockExtendPeriod = time.Millisecond
+)
+
+// MinLockTTL is the shortest lock TTL, the one of the shortest extend period.
+// It only keeps the TTL and its extension valid, a usable TTL is much longer
+// than the round trip to redis.
+const MinLockTTL = lockExtendDivisor * minLockExtendPeriod
+
+func validateLockTTL(ttl time.Duration) error {
+	if ttl < MinLockTTL {
+		return fmt.Errorf("%w: %s is shorter than %s", ErrInvalidLockTTL, ttl, MinLockTTL)
+	}
+
+	return nil
+}
+
+// unlockScriptSrc deletes KEYS[1] when it still holds the token ARGV[1].
+const unlockScriptSrc = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`
+
+// extendScriptSrc sets the time to live of KEYS[1] to ARGV[2] milliseconds
+// when it still holds the token ARGV[1].
+const extendScriptSrc = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`
+
+var (
+	unlockScript = redis.NewScript(unlockScriptSrc)
+	extendScript = redis.NewScript(extendScriptSrc)
+)
+
+// LockOptions tunes the acquisition of a lock. Lock retries with an
+// exponential backoff between MinBackoff and MaxBackoff until ctx is done.
+// Without NoAutoExtend the lock is extended every third of its TTL while it
+// is held.
+type LockOptions struct {
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	NoAutoExtend bool
+}
+
+// Locker hands out mutually exclusive locks across the instances sharing the
+// redis server.
+type Locker struct {
+	client RedisClient
+}
+
+func NewLocker(client RedisClient) *Locker {
+	return &Locker{client: client}
+}
+
+// Lock acquires the lock key for ttl, it retries until ctx is done and then
+// returns ErrLockNotAcquired. A ttl shorter than MinLockTTL is rejected with
+// ErrInvalidLockTTL.
+func (l *Locker) Lock(ctx context.Context, key string, ttl time.Duration, opts ...LockOptions) (*Lock, error) {
+	if err := validateLockTTL(ttl); err != nil {
+		return nil, err
+	}
+
+	var opt LockOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	minBackoff := opt.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultLockMinBackoff
+	}
+	maxBackoff := opt.MaxBackoff
+	if maxBackoff < minBackoff {
+		maxBackoff = max(defaultLockMaxBackoff, minBackoff)
+	}
+
+	backoff := minBackoff
+	for {
+		lock, err := l.TryLock(ctx, key, ttl, opt)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		// the jitter spreads the retries of the instances waiting for the lock
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", ErrLockNotAcquired, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// TryLock acquires the lock key for ttl in a single attempt, it returns
+// ErrLockNotAcquired when another holder has it.
+func (l *Locker) TryLock(ctx context.Context, key string, ttl time.Duration, opts ...LockOptions) (*Lock, error) {
+	if err := validateLockTTL(ttl); err != nil {
+		return nil, err
+	}
+
+	token := uuid.NewString()
+
+	ok, err := l.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &Lock{client: l.client, key: key, token: token, ttl: ttl, lost: make(chan struct{})}
+	if len(opts) == 0 || !opts[0].NoAutoExtend {
+		lock.startAutoExtend()
+	}
+
+	return lock, nil
+}
+
+// Lock is a held lock.
+type Lock struct {
+	client RedisClient
+	key    string
+	token  string
+	ttl    time.Duration
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Lost is closed when the automatic extension finds that the lock is not held
+// anymore, or could not extend it before it expired.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Extend sets the time to live of the lock to ttl, it returns ErrLockNotHeld
+// when the lock expired or was taken by another holder.
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	extended, err := extendScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if extended == 0 {
+		return ErrLockNotHeld
+	}
+
+	return nil
+}
+
+// Release stops the automatic extension and releases the lock, it returns
+// ErrLockNotHeld when the lock was not held anymore.
+func (l *Lock) Release(ctx context.Context) error {
+	l.stopAutoExtend()
+
+	released, err := unlockScript.Run(ctx, l.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrLockNotHeld
+	}
+
+	return nil
+}
+
+func (l *Lock) markLost() {
+	l.lostOnce.Do(func() {
+		common_utils.LogError(fmt.Sprintf("lost lock: %s", l.key))
+		close(l.lost)
+	})
+}
+
+func (l *Lock) startAutoExtend() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(l.ttl / lockExtendDivisor)
+		defer ticker.Stop()
+
+		extendedAt := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := l.Extend(ctx, l.ttl)
+			switch {
+			case err == nil:
+				extendedAt = time.Now()
+			case errors.Is(err, ErrLockNotHeld):
+				l.markLost()
+				return
+			case ctx.Err() != nil:
+				return
+			default:
+				common_utils.LogError(fmt.Sprintf("failed extend lock %s: %v", l.key, err))
+				if time.Since(extendedAt) >= l.ttl {
+					l.markLost()
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (l *Lock) stopAutoExtend() {
+	if l.cancel == nil {
+		return
+	}
+
+	l.cancel()
+	<-l.done
+}
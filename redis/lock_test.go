@@ -0,0 +1,150 @@
+package redis_client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	redisClient := newFakeRedis()
+	locker := NewLocker(redisClient)
+
+	t.Run("Exclude other holders until released", func(t *testing.T) {
+		lock, err := locker.TryLock(ctx, "lock-job", time.Minute)
+		assert.NoError(t, err)
+
+		_, err = locker.TryLock(ctx, "lock-job", time.Minute)
+		assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = locker.Lock(waitCtx, "lock-job", time.Minute)
+		assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+		assert.NoError(t, lock.Release(ctx))
+		assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+
+		lock, err = locker.TryLock(ctx, "lock-job", time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, lock.Release(ctx))
+	})
+
+	t.Run("Acquire once the holder releases", func(t *testing.T) {
+		lock, err := locker.TryLock(ctx, "lock-wait", time.Minute)
+		assert.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			lock.Release(ctx)
+		}()
+
+		waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		next, err := locker.Lock(waitCtx, "lock-wait", time.Minute, LockOptions{MinBackoff: 10 * time.Millisecond})
+		assert.NoError(t, err)
+		assert.NoError(t, next.Release(ctx))
+	})
+
+	t.Run("Not release a lock taken by another holder", func(t *testing.T) {
+		lock, err := locker.TryLock(ctx, "lock-expired", time.Minute, LockOptions{NoAutoExtend: true})
+		assert.NoError(t, err)
+
+		// the lock expired and another holder took it
+		redisClient.Del(ctx, "lock-expired")
+		other, err := locker.TryLock(ctx, "lock-expired", time.Minute)
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, lock.Extend(ctx, time.Minute), ErrLockNotHeld)
+		assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+		assert.NoError(t, other.Release(ctx))
+	})
+
+	t.Run("Extend the lock while held and report its loss", func(t *testing.T) {
+		lock, err := locker.TryLock(ctx, "lock-extend", 30*time.Millisecond)
+		assert.NoError(t, err)
+
+		redisClient.Set(ctx, "lock-extend", "other-holder", time.Minute)
+		select {
+		case <-lock.Lost():
+		case <-time.After(time.Second):
+			t.Fatal("lock loss not reported")
+		}
+		assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+	})
+
+	t.Run("Reject a ttl too short to expire and extend", func(t *testing.T) {
+		for _, ttl := range []time.Duration{-time.Second, 0, time.Nanosecond, time.Millisecond, MinLockTTL - time.Nanosecond} {
+			_, err := locker.TryLock(ctx, "lock-ttl", ttl)
+			assert.ErrorIs(t, err, ErrInvalidLockTTL)
+			_, err = locker.Lock(ctx, "lock-ttl", ttl)
+			assert.ErrorIs(t, err, ErrInvalidLockTTL)
+		}
+
+		assert.Equal(t, minLockExtendPeriod, MinLockTTL/lockExtendDivisor)
+		lock, err := locker.TryLock(ctx, "lock-ttl", MinLockTTL, LockOptions{NoAutoExtend: true})
+		if assert.NoError(t, err) {
+			lock.Release(ctx)
+		}
+	})
+}
+
+func TestLeaderElector(t *testing.T) {
+	redisClient := newFakeRedis()
+	locker := NewLocker(redisClient)
+
+	var leading atomic.Int32
+	var maxLeading atomic.Int32
+	var stopped atomic.Int32
+	newElector := func() *LeaderElector {
+		elector, err := NewLeaderElector(locker, "leader-job", 40*time.Millisecond, LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				if n := leading.Add(1); n > maxLeading.Load() {
+					maxLeading.Store(n)
+				}
+				<-ctx.Done()
+				leading.Add(-1)
+			},
+			OnStoppedLeading: func() {
+				stopped.Add(1)
+			},
+		})
+		assert.NoError(t, err)
+		return elector
+	}
+
+	_, err := NewLeaderElector(locker, "leader-job", 0, LeaderCallbacks{})
+	assert.ErrorIs(t, err, ErrInvalidLockTTL)
+
+	first, second := newElector(), newElector()
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	firstDone, secondDone := make(chan struct{}), make(chan struct{})
+	go func() { first.Run(firstCtx); close(firstDone) }()
+	go func() { second.Run(secondCtx); close(secondDone) }()
+
+	assert.Eventually(t, func() bool { return first.IsLeader() || second.IsLeader() }, time.Second, 5*time.Millisecond)
+	leader, leaderDone, stopLeader, follower := first, firstDone, stopFirst, second
+	if second.IsLeader() {
+		leader, leaderDone, stopLeader, follower = second, secondDone, stopSecond, first
+	}
+	assert.False(t, follower.IsLeader())
+
+	stopLeader()
+	<-leaderDone
+	assert.False(t, leader.IsLeader())
+	assert.Equal(t, int32(1), stopped.Load())
+
+	assert.Eventually(t, follower.IsLeader, time.Second, 5*time.Millisecond)
+	stopFirst()
+	stopSecond()
+	<-firstDone
+	<-secondDone
+
+	assert.Equal(t, int32(1), maxLeading.Load())
+	assert.Equal(t, int32(2), stopped.Load())
+}
@@ -0,0 +1,157 @@
+package redis_client
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// lruFill tracks the reads of a key that fill the cache. Every removal of the
+// key increments gen, a fill that started before is dropped.
+type lruFill struct {
+	gen   uint64
+	reads int
+}
+
+// lruCache is a size bounded in-memory cache whose entries expire, the least
+// recently used entry is evicted when it is full.
+type lruCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	fills      map[string]*lruFill
+}
+
+func newLRUCache(maxEntries int) *lruCache {
+	return &lruCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		fills:      make(map[string]*lruFill),
+	}
+}
+
+func (c *lruCache) get(key string, now time.Time) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if !now.Before(entry.expiresAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruCache) set(key string, value []byte, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setLocked(key, value, expiresAt)
+}
+
+// startFill returns the generation of key before it is read to fill the
+// cache, endFill must be called once the read is done.
+func (c *lruCache) startFill(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fill, ok := c.fills[key]
+	if !ok {
+		fill = &lruFill{}
+		c.fills[key] = fill
+	}
+	fill.reads++
+	return fill.gen
+}
+
+func (c *lruCache) endFill(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fill, ok := c.fills[key]; ok {
+		if fill.reads--; fill.reads == 0 {
+			delete(c.fills, key)
+		}
+	}
+}
+
+// fill sets key unless it was removed since startFill returned gen.
+func (c *lruCache) fill(key string, gen uint64, value []byte, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fill, ok := c.fills[key]; ok && fill.gen != gen {
+		return
+	}
+	c.setLocked(key, value, expiresAt)
+}
+
+func (c *lruCache) setLocked(key string, value []byte, expiresAt time.Time) {
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *lruCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fill, ok := c.fills[key]; ok {
+		fill.gen++
+	}
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lruCache) removePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, fill := range c.fills {
+		if strings.HasPrefix(key, prefix) {
+			fill.gen++
+		}
+	}
+	for key, elem := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,92 @@
+package redis_client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// universalOptions returns the client options of the REDIS_* config. The
+// nodes are REDIS_ADDRS, or REDIS_HOST:REDIS_PORT when it is empty: the
+// cluster seed nodes in cluster mode and the sentinels in sentinel mode.
+func universalOptions(config *common_utils.BaseConfig) (*redis.UniversalOptions, error) {
+	addrs := config.RedisAddrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort)}
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := baseOptions(config)
+	opts.Addrs = addrs
+	opts.TLSConfig = tlsConfig
+
+	switch config.RedisMode {
+	case "", RedisModeStandalone:
+		opts.MasterName = ""
+	case RedisModeSentinel:
+		if opts.MasterName == "" {
+			return nil, errors.New("redis sentinel mode requires REDIS_MASTER_NAME")
+		}
+	case RedisModeCluster:
+		if opts.DB != 0 {
+			return nil, errors.New("redis cluster mode only supports DB 0")
+		}
+		opts.MasterName = ""
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", config.RedisMode)
+	}
+
+	return opts, nil
+}
+
+// baseOptions returns the client options of the REDIS_* config which do not
+// depend on the mode and cannot be invalid.
+func baseOptions(config *common_utils.BaseConfig) *redis.UniversalOptions {
+	return &redis.UniversalOptions{
+		Username:         config.RedisUser,
+		Password:         config.RedisPassword,
+		DB:               config.RedisDb,
+		PoolSize:         config.RedisPoolSize,
+		MinIdleConns:     config.RedisMinIdleConns,
+		DialTimeout:      config.RedisDialTimeout,
+		ReadTimeout:      config.RedisReadTimeout,
+		WriteTimeout:     config.RedisWriteTimeout,
+		PoolTimeout:      config.RedisPoolTimeout,
+		MasterName:       config.RedisMasterName,
+		SentinelPassword: config.RedisSentinelPassword,
+	}
+}
+
+func newUniversalClient(config *common_utils.BaseConfig, opts *redis.UniversalOptions) redis.UniversalClient {
+	switch config.RedisMode {
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(opts.Failover())
+	case RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster())
+	}
+
+	return redis.NewClient(opts.Simple())
+}
+
+func newTLSConfig(config *common_utils.BaseConfig) (*tls.Config, error) {
+	return common_utils.NewTLSConfig(common_utils.TLSOptions{
+		Enable:     config.RedisTlsEnable,
+		SkipVerify: config.RedisTlsSkipVerify,
+		CaFile:     config.RedisTlsCaFile,
+		CertFile:   config.RedisTlsCertFile,
+		KeyFile:    config.RedisTlsKeyFile,
+	})
+}
@@ -0,0 +1,74 @@
+package redis_client
+
+import (
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisClientModes(t *testing.T) {
+	t.Run("Standalone from host and port", func(t *testing.T) {
+		config := &common_utils.BaseConfig{RedisHost: "127.0.0.1", RedisPort: "6379", RedisDb: 2, RedisPoolSize: 20, RedisReadTimeout: time.Second}
+
+		opts, err := universalOptions(config)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"127.0.0.1:6379"}, opts.Addrs)
+
+		client, err := NewUniversalRedisClient(config)
+		assert.NoError(t, err)
+		defer client.Close()
+		assert.IsType(t, &redis.Client{}, client)
+
+		clientOpts := client.(*redis.Client).Options()
+		assert.Equal(t, 2, clientOpts.DB)
+		assert.Equal(t, 20, clientOpts.PoolSize)
+		assert.Equal(t, time.Second, clientOpts.ReadTimeout)
+	})
+
+	t.Run("Standalone without mode", func(t *testing.T) {
+		config := &common_utils.BaseConfig{RedisMode: RedisModeCluster, RedisHost: "127.0.0.1", RedisPort: "6379", RedisDb: 2}
+
+		client := NewRedisClient(config)
+		defer client.Close()
+		assert.Equal(t, "127.0.0.1:6379", client.Options().Addr)
+		assert.Equal(t, 2, client.Options().DB)
+
+		config.RedisTlsEnable, config.RedisTlsCaFile = true, "does-not-exist.pem"
+		assert.NotPanics(t, func() { NewRedisClient(config).Close() })
+		_, err := NewUniversalRedisClient(config)
+		assert.Error(t, err)
+	})
+
+	t.Run("Sentinel", func(t *testing.T) {
+		config := &common_utils.BaseConfig{RedisMode: RedisModeSentinel, RedisAddrs: []string{"s1:26379", "s2:26379"}, RedisMasterName: "mymaster"}
+
+		client, err := NewUniversalRedisClient(config)
+		assert.NoError(t, err)
+		defer client.Close()
+		assert.IsType(t, &redis.Client{}, client)
+
+		_, err = universalOptions(&common_utils.BaseConfig{RedisMode: RedisModeSentinel})
+		assert.Error(t, err)
+	})
+
+	t.Run("Cluster", func(t *testing.T) {
+		config := &common_utils.BaseConfig{RedisMode: RedisModeCluster, RedisAddrs: []string{"n1:6379"}, RedisTlsEnable: true}
+
+		client, err := NewUniversalRedisClient(config)
+		assert.NoError(t, err)
+		defer client.Close()
+		assert.IsType(t, &redis.ClusterClient{}, client)
+		assert.NotNil(t, client.(*redis.ClusterClient).Options().TLSConfig)
+
+		_, err = universalOptions(&common_utils.BaseConfig{RedisMode: RedisModeCluster, RedisDb: 1})
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		_, err := universalOptions(&common_utils.BaseConfig{RedisMode: "replica"})
+		assert.Error(t, err)
+	})
+}
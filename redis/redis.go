@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 	"time"
 
 	common_utils "github.com/dispenal/go-common/utils"
@@ -15,20 +16,31 @@ import (
 type RedisClient interface {
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	TTL(ctx context.Context, key string) *redis.DurationCmd
 	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	redis.Scripter
 	Close() error
 }
 
 type CacheSvc interface {
 	Set(ctx context.Context, key string, data any, duration ...time.Duration) error
+	SetNX(ctx context.Context, key string, data any, duration time.Duration) (bool, error)
 	Get(ctx context.Context, key string, output any) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
 	Del(ctx context.Context, key string) error
-	DelByPrefix(ctx context.Context, prefixName string)
+	DelIfEqual(ctx context.Context, key string, data any) (bool, error)
+	DelByPrefix(ctx context.Context, prefixName string) error
+	SetWithTags(ctx context.Context, key string, data any, tags []string, duration ...time.Duration) error
+	InvalidateTags(ctx context.Context, tags ...string) (int64, error)
 	GetOrSet(ctx context.Context, key string, function func() any, duration ...time.Duration) (any, error)
 	CloseClient() error
 }
 
+const delByPrefixBatch = 500
+
 type CacheSvcImpl struct {
 	config  *common_utils.BaseConfig
 	cacheDb RedisClient
@@ -41,14 +53,32 @@ func NewCacheSvc(config *common_utils.BaseConfig, cacheDb RedisClient) CacheSvc
 	}
 }
 
+// NewRedisClient returns a standalone client of REDIS_HOST:REDIS_PORT, it
+// ignores REDIS_MODE. An invalid TLS config is logged and the client connects
+// without TLS, use NewUniversalRedisClient to handle the error.
 func NewRedisClient(config *common_utils.BaseConfig) *redis.Client {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort),
-		Password: config.RedisPassword, // no password set
-		DB:       0,                    // use default DB
-		Username: config.RedisUser,
-	})
-	return rdb
+	opts := baseOptions(config)
+	opts.Addrs = []string{fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort)}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		common_utils.LogError(fmt.Sprintf("invalid redis tls config, connecting without: %v", err))
+	}
+	opts.TLSConfig = tlsConfig
+
+	return redis.NewClient(opts.Simple())
+}
+
+// NewUniversalRedisClient returns a standalone, sentinel or cluster client
+// depending on REDIS_MODE, all of them work with CacheSvc. An invalid mode or
+// TLS config is returned as error.
+func NewUniversalRedisClient(config *common_utils.BaseConfig) (redis.UniversalClient, error) {
+	opts, err := universalOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUniversalClient(config, opts), nil
 }
 
 func NewRedisClientForTesting(config *common_utils.BaseConfig) *redis.Client {
@@ -62,50 +92,69 @@ func NewRedisClientForTesting(config *common_utils.BaseConfig) *redis.Client {
 }
 
 func (s *CacheSvcImpl) Set(ctx context.Context, key string, data any, duration ...time.Duration) error {
+	cacheData, err := s.marshalCacheData(key, data)
+	if err != nil || cacheData == nil {
+		return err
+	}
+
+	common_utils.LogInfo(fmt.Sprintf("set data to cache with key --> %s", key))
+
+	return s.cacheDb.Set(ctx, key, cacheData, s.expiration(duration...)).Err()
+}
+
+// marshalCacheData returns the cached form of data, or nil when data is not
+// cached.
+func (s *CacheSvcImpl) marshalCacheData(key string, data any) ([]byte, error) {
 	dataErr, isDataErr := data.(error)
 	if isDataErr {
 		common_utils.LogInfo("not save data to cache (data error)")
-		return dataErr
+		return nil, dataErr
 	}
 
 	appErr, isAppErr := data.(common_utils.AppError)
 	if isAppErr {
 		common_utils.LogInfo("not save data to cache (app error)")
-		return &appErr
+		return nil, &appErr
 	}
 
 	validationErrs, isValidationErrs := data.(common_utils.ValidationErrors)
 	if isValidationErrs {
 		common_utils.LogInfo("not save data to cache (validation errors)")
-		return &validationErrs
+		return nil, &validationErrs
 	}
 
 	if data != nil {
 		if reflect.TypeOf(data).Kind() == reflect.Slice {
 			if reflect.ValueOf(data).Len() == 0 {
 				common_utils.LogInfo("no data to save, array is empty")
-				return nil
+				return nil, nil
 			}
 		}
 
-		cacheData, err := common_utils.Marshal(data)
-		if err != nil {
-			return err
-		}
+		return common_utils.Marshal(data)
+	}
 
-		common_utils.LogInfo(fmt.Sprintf("set data to cache with key --> %s", key))
+	common_utils.LogInfo(fmt.Sprintf("not save data to cache, key --> %s", key))
 
-		expiration := time.Duration(s.config.RedisCacheExpire) * time.Second
-		if len(duration) > 0 {
-			expiration = duration[0]
-		}
+	return nil, nil
+}
 
-		return s.cacheDb.Set(ctx, key, cacheData, expiration).Err()
+func (s *CacheSvcImpl) expiration(duration ...time.Duration) time.Duration {
+	if len(duration) > 0 {
+		return duration[0]
 	}
 
-	common_utils.LogInfo(fmt.Sprintf("not save data to cache, key --> %s", key))
+	return time.Duration(s.config.RedisCacheExpire) * time.Second
+}
 
-	return nil
+// SetNX sets key only when it does not exist and reports whether it was set.
+func (s *CacheSvcImpl) SetNX(ctx context.Context, key string, data any, duration time.Duration) (bool, error) {
+	cacheData, err := common_utils.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	return s.cacheDb.SetNX(ctx, key, cacheData, duration).Result()
 }
 
 func (s *CacheSvcImpl) Get(ctx context.Context, key string, output any) error {
@@ -127,24 +176,84 @@ func (s *CacheSvcImpl) Get(ctx context.Context, key string, output any) error {
 	return nil
 }
 
-func (s *CacheSvcImpl) DelByPrefix(ctx context.Context, prefixName string) {
-	var foundedRecordCount int = 0
-	iter := s.cacheDb.Scan(ctx, 0, fmt.Sprintf("%s*", prefixName), 0).Iterator()
+// DelByPrefix deletes the keys starting with prefixName. The keys are scanned
+// and unlinked in pipelined batches of delByPrefixBatch keys, on every master
+// of a cluster.
+func (s *CacheSvcImpl) DelByPrefix(ctx context.Context, prefixName string) error {
 	common_utils.LogInfo(fmt.Sprintf("your search pattern: %s", prefixName))
 
+	var deleted atomic.Int64
+	var err error
+	if cluster, ok := s.cacheDb.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return s.delByPrefix(ctx, node, prefixName, &deleted)
+		})
+	} else {
+		err = s.delByPrefix(ctx, s.cacheDb, prefixName, &deleted)
+	}
+
+	if err != nil {
+		common_utils.LogError("failed when deleting cache", zap.Error(err))
+		return err
+	}
+
+	common_utils.LogInfo(fmt.Sprintf("deleted Count %d", deleted.Load()))
+
+	return nil
+}
+
+func (s *CacheSvcImpl) delByPrefix(ctx context.Context, client RedisClient, prefixName string, deleted *atomic.Int64) error {
+	keys := make([]string, 0, delByPrefixBatch)
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+
+		count, err := s.unlink(ctx, client, keys)
+		deleted.Add(count)
+		keys = keys[:0]
+		return err
+	}
+
+	iter := client.Scan(ctx, 0, fmt.Sprintf("%s*", prefixName), delByPrefixBatch).Iterator()
 	for iter.Next(ctx) {
-		common_utils.LogInfo(fmt.Sprintf("deleted= %s", iter.Val()))
-		s.cacheDb.Del(ctx, iter.Val())
-		foundedRecordCount++
+		keys = append(keys, iter.Val())
+		if len(keys) < delByPrefixBatch {
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
 	}
 
 	if err := iter.Err(); err != nil {
-		common_utils.LogError("failed when deleting cache", zap.Error(err))
+		return err
+	}
+
+	return flush()
+}
+
+func (s *CacheSvcImpl) unlink(ctx context.Context, client RedisClient, keys []string) (int64, error) {
+	cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Unlink(ctx, key)
+		}
+		return nil
+	})
+
+	var deleted int64
+	for _, cmd := range cmds {
+		if intCmd, ok := cmd.(*redis.IntCmd); ok {
+			deleted += intCmd.Val()
+		}
 	}
 
-	common_utils.LogInfo(fmt.Sprintf("deleted Count %d", foundedRecordCount))
+	return deleted, err
 }
 
+// Deprecated: GetOrSet loses the type of the cached value and runs function
+// on every concurrent miss, use GetOrLoad instead.
 func (s *CacheSvcImpl) GetOrSet(ctx context.Context, key string, function func() any, duration ...time.Duration) (any, error) {
 	var data any
 	err := s.Get(ctx, key, &data)
@@ -163,6 +272,12 @@ func (s *CacheSvcImpl) GetOrSet(ctx context.Context, key string, function func()
 	return data, nil
 }
 
+// TTL returns the remaining time to live of key, -1 when it does not expire
+// and -2 when it does not exist.
+func (s *CacheSvcImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return s.cacheDb.TTL(ctx, key).Result()
+}
+
 func (s *CacheSvcImpl) Del(ctx context.Context, key string) error {
 	err := s.cacheDb.Del(ctx, key).Err()
 	if err != nil {
@@ -173,6 +288,18 @@ func (s *CacheSvcImpl) Del(ctx context.Context, key string) error {
 	return nil
 }
 
+// DelIfEqual deletes key only when it holds data, e.g. the token set with
+// SetNX, and reports whether it was deleted.
+func (s *CacheSvcImpl) DelIfEqual(ctx context.Context, key string, data any) (bool, error) {
+	cacheData, err := common_utils.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := unlockScript.Run(ctx, s.cacheDb, []string{key}, cacheData).Int64()
+	return deleted > 0, err
+}
+
 func (s *CacheSvcImpl) CloseClient() error {
 	return s.cacheDb.Close()
 }
@@ -3,6 +3,7 @@ package redis_client
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -233,6 +234,25 @@ func TestCacheSvc(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("Invalidate the keys of a tag", func(t *testing.T) {
+		err := cacheSvc.SetWithTags(ctx, STRUCT_KEY, dataStruct, []string{"user:1"}, time.Minute)
+		assert.NoError(t, err)
+		err = cacheSvc.SetWithTags(ctx, SLICE_KEY, dataSlice, []string{"user:1", "orders"})
+		assert.NoError(t, err)
+
+		deleted, err := cacheSvc.InvalidateTags(ctx, "user:1")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		output := testData{}
+		err = cacheSvc.Get(ctx, STRUCT_KEY, &output)
+		assert.Equal(t, redis.Nil, err)
+
+		deleted, err = cacheSvc.InvalidateTags(ctx, "orders")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), deleted)
+	})
+
 	t.Run("Close client", func(t *testing.T) {
 		err := cacheSvc.CloseClient()
 		assert.NoError(t, err)
@@ -241,3 +261,21 @@ func TestCacheSvc(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestDelByPrefix(t *testing.T) {
+	ctx := context.Background()
+	config := &common_utils.BaseConfig{RedisCacheExpire: 60}
+	redisClient := newFakeRedis()
+	cacheSvc := NewCacheSvc(config, redisClient)
+
+	for i := 0; i < 2*delByPrefixBatch+1; i++ {
+		assert.NoError(t, cacheSvc.Set(ctx, fmt.Sprintf("%s-%d", CACHE, i), i))
+	}
+	assert.NoError(t, cacheSvc.Set(ctx, "other-key", 1))
+
+	assert.NoError(t, cacheSvc.DelByPrefix(ctx, CACHE))
+
+	keys, err := redisClient.Keys(ctx, "*").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other-key"}, keys)
+}
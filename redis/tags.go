@@ -0,0 +1,106 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix prefixes the sets holding the keys of a tag.
+const tagKeyPrefix = "cache-tag:"
+
+func tagKey(tag string) string {
+	return tagKeyPrefix + tag
+}
+
+// setWithTagsScript sets KEYS[1] to ARGV[1] for ARGV[2] milliseconds, or
+// without expiration when it is 0, and adds it to the tag sets KEYS[2:]. A tag
+// set lives as long as its longest living key.
+var setWithTagsScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+
+for i = 2, #KEYS do
+	local existed = redis.call("EXISTS", KEYS[i])
+	redis.call("SADD", KEYS[i], KEYS[1])
+	if ttl <= 0 then
+		redis.call("PERSIST", KEYS[i])
+	else
+		local current = redis.call("PTTL", KEYS[i])
+		if existed == 0 or (current >= 0 and current < ttl) then
+			redis.call("PEXPIRE", KEYS[i], ttl)
+		end
+	end
+end
+
+return 1
+`)
+
+// invalidateTagsScript deletes the keys of the tag sets KEYS and the sets, it
+// returns the number of deleted keys.
+var invalidateTagsScript = redis.NewScript(`
+local deleted = 0
+for i = 1, #KEYS do
+	local members = redis.call("SMEMBERS", KEYS[i])
+	for j = 1, #members, 500 do
+		deleted = deleted + redis.call("UNLINK", unpack(members, j, math.min(j + 499, #members)))
+	end
+	redis.call("UNLINK", KEYS[i])
+end
+
+return deleted
+`)
+
+// SetWithTags sets key like Set and attaches it to tags, InvalidateTags
+// deletes the keys of a tag. On a cluster the scripts need the key and its
+// tags in the same slot, give them a common hash tag like "{user:1}".
+func (s *CacheSvcImpl) SetWithTags(ctx context.Context, key string, data any, tags []string, duration ...time.Duration) error {
+	if len(tags) == 0 {
+		return s.Set(ctx, key, data, duration...)
+	}
+
+	cacheData, err := s.marshalCacheData(key, data)
+	if err != nil || cacheData == nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tags)+1)
+	keys = append(keys, key)
+	for _, tag := range tags {
+		keys = append(keys, tagKey(tag))
+	}
+
+	common_utils.LogInfo(fmt.Sprintf("set data to cache with key --> %s, tags --> %v", key, tags))
+
+	return setWithTagsScript.Run(ctx, s.cacheDb, keys, cacheData, s.expiration(duration...).Milliseconds()).Err()
+}
+
+// InvalidateTags atomically deletes the keys attached to tags and returns the
+// number of deleted keys.
+func (s *CacheSvcImpl) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagKey(tag)
+	}
+
+	deleted, err := invalidateTagsScript.Run(ctx, s.cacheDb, keys).Int64()
+	if err != nil {
+		common_utils.LogError(fmt.Sprintf("failed when invalidating cache tags: %v", tags))
+		return 0, err
+	}
+
+	common_utils.LogInfo(fmt.Sprintf("invalidated tags %v, deleted Count %d", tags, deleted))
+
+	return deleted, nil
+}
@@ -0,0 +1,275 @@
+package redis_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// InvalidationBus fans out the invalidations of a TieredCacheSvc to the other
+// instances.
+type InvalidationBus interface {
+	Publish(ctx context.Context, message []byte) error
+	// Subscribe calls handler with every published message until ctx is done.
+	Subscribe(ctx context.Context, handler func(message []byte)) error
+}
+
+// RedisPubSub is the part of the redis client used by the invalidation bus.
+type RedisPubSub interface {
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+type redisInvalidationBus struct {
+	client  RedisPubSub
+	channel string
+}
+
+// NewRedisInvalidationBus publishes the invalidations on the redis pub/sub
+// channel. Messages published while an instance is disconnected are lost, its
+// entries stay stale until their L1 TTL.
+func NewRedisInvalidationBus(client RedisPubSub, channel string) InvalidationBus {
+	return &redisInvalidationBus{client: client, channel: channel}
+}
+
+func (b *redisInvalidationBus) Publish(ctx context.Context, message []byte) error {
+	return b.client.Publish(ctx, b.channel, message).Err()
+}
+
+func (b *redisInvalidationBus) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handler([]byte(msg.Payload))
+		}
+	}
+}
+
+type invalidation struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key"`
+	Prefix bool   `json:"prefix,omitempty"`
+}
+
+// TieredCacheSvc keeps the values read from the redis CacheSvc in an
+// in-process LRU cache. Entries live for the L1 TTL, capped by their redis
+// TTL. Set, Del and DelByPrefix evict the entries of every instance through
+// the InvalidationBus.
+type TieredCacheSvc struct {
+	config *common_utils.BaseConfig
+	cache  CacheSvc
+	bus    InvalidationBus
+	l1     *lruCache
+	ttl    time.Duration
+	origin string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+const defaultL1Ttl = time.Minute
+
+// NewTieredCacheSvc layers an L1 cache of REDIS_L1_MAX_ENTRIES entries over
+// cache. Without a bus the invalidations stay local.
+func NewTieredCacheSvc(config *common_utils.BaseConfig, cache CacheSvc, bus InvalidationBus) CacheSvc {
+	ttl := config.RedisL1Ttl
+	if ttl <= 0 {
+		ttl = defaultL1Ttl
+	}
+
+	s := &TieredCacheSvc{
+		config: config,
+		cache:  cache,
+		bus:    bus,
+		l1:     newLRUCache(config.RedisL1MaxEntries),
+		ttl:    ttl,
+		origin: uuid.NewString(),
+		done:   make(chan struct{}),
+	}
+
+	if bus == nil {
+		close(s.done)
+		return s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		defer close(s.done)
+		common_utils.LogIfError(bus.Subscribe(ctx, s.handleInvalidation))
+	}()
+
+	return s
+}
+
+func (s *TieredCacheSvc) handleInvalidation(message []byte) {
+	var inv invalidation
+	if err := common_utils.Unmarshal(message, &inv); err != nil {
+		common_utils.LogError(fmt.Sprintf("failed unmarshal cache invalidation: %v", err))
+		return
+	}
+
+	if inv.Origin == s.origin {
+		return
+	}
+	s.evict(inv.Key, inv.Prefix)
+}
+
+func (s *TieredCacheSvc) evict(key string, prefix bool) {
+	if prefix {
+		s.l1.removePrefix(key)
+		return
+	}
+	s.l1.remove(key)
+}
+
+// invalidate evicts key locally and on the other instances.
+func (s *TieredCacheSvc) invalidate(ctx context.Context, key string, prefix bool) {
+	s.evict(key, prefix)
+	if s.bus == nil {
+		return
+	}
+
+	message, err := common_utils.Marshal(invalidation{Origin: s.origin, Key: key, Prefix: prefix})
+	if err != nil {
+		common_utils.LogIfError(err)
+		return
+	}
+	common_utils.LogIfError(s.bus.Publish(ctx, message))
+}
+
+func (s *TieredCacheSvc) Set(ctx context.Context, key string, data any, duration ...time.Duration) error {
+	err := s.cache.Set(ctx, key, data, duration...)
+	s.invalidate(ctx, key, false)
+
+	return err
+}
+
+func (s *TieredCacheSvc) SetNX(ctx context.Context, key string, data any, duration time.Duration) (bool, error) {
+	ok, err := s.cache.SetNX(ctx, key, data, duration)
+	if ok {
+		s.invalidate(ctx, key, false)
+	}
+
+	return ok, err
+}
+
+func (s *TieredCacheSvc) Get(ctx context.Context, key string, output any) error {
+	now := time.Now()
+	if value, ok := s.l1.get(key, now); ok {
+		return json.Unmarshal(value, &output)
+	}
+
+	// an invalidation of key during the read drops the fill, the value read
+	// may be older than the write that invalidated it
+	gen := s.l1.startFill(key)
+	defer s.l1.endFill(key)
+
+	var raw json.RawMessage
+	if err := s.cache.Get(ctx, key, &raw); err != nil {
+		return err
+	}
+
+	ttl, err := s.cache.TTL(ctx, key)
+	switch {
+	case err != nil:
+		common_utils.LogError(fmt.Sprintf("failed get ttl of cache key %s: %v", key, err))
+	case ttl == -2:
+		// the key expired after the read
+	case ttl < 0 || ttl > s.ttl:
+		s.l1.fill(key, gen, raw, now.Add(s.ttl))
+	default:
+		s.l1.fill(key, gen, raw, now.Add(ttl))
+	}
+
+	return json.Unmarshal(raw, &output)
+}
+
+func (s *TieredCacheSvc) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return s.cache.TTL(ctx, key)
+}
+
+func (s *TieredCacheSvc) Del(ctx context.Context, key string) error {
+	err := s.cache.Del(ctx, key)
+	s.invalidate(ctx, key, false)
+
+	return err
+}
+
+func (s *TieredCacheSvc) DelIfEqual(ctx context.Context, key string, data any) (bool, error) {
+	deleted, err := s.cache.DelIfEqual(ctx, key, data)
+	if deleted {
+		s.invalidate(ctx, key, false)
+	}
+
+	return deleted, err
+}
+
+func (s *TieredCacheSvc) DelByPrefix(ctx context.Context, prefixName string) error {
+	err := s.cache.DelByPrefix(ctx, prefixName)
+	s.invalidate(ctx, prefixName, true)
+
+	return err
+}
+
+func (s *TieredCacheSvc) SetWithTags(ctx context.Context, key string, data any, tags []string, duration ...time.Duration) error {
+	err := s.cache.SetWithTags(ctx, key, data, tags, duration...)
+	s.invalidate(ctx, key, false)
+
+	return err
+}
+
+// InvalidateTags deletes the keys of tags. The L1 caches do not know the tags
+// of their entries, they are all emptied.
+func (s *TieredCacheSvc) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	deleted, err := s.cache.InvalidateTags(ctx, tags...)
+	s.invalidate(ctx, "", true)
+
+	return deleted, err
+}
+
+func (s *TieredCacheSvc) GetOrSet(ctx context.Context, key string, function func() any, duration ...time.Duration) (any, error) {
+	var data any
+	err := s.Get(ctx, key, &data)
+
+	if errors.Is(err, redis.Nil) {
+		data = function()
+		err := s.Set(ctx, key, data, duration...)
+
+		return data, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// CloseClient stops receiving the invalidations and closes the redis client.
+func (s *TieredCacheSvc) CloseClient() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	<-s.done
+
+	return s.cache.CloseClient()
+}
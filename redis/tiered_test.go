@@ -0,0 +1,154 @@
+package redis_client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	common_utils "github.com/dispenal/go-common/utils"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBus delivers the published messages to the subscribers synchronously.
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers []func(message []byte)
+	ready    sync.WaitGroup
+}
+
+func newMemoryBus(subscribers int) *memoryBus {
+	b := &memoryBus{}
+	b.ready.Add(subscribers)
+	return b
+}
+
+func (b *memoryBus) Publish(ctx context.Context, message []byte) error {
+	b.mu.Lock()
+	handlers := append([]func(message []byte){}, b.handlers...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+	b.ready.Done()
+
+	<-ctx.Done()
+	return nil
+}
+
+// ttlHookCacheSvc calls onTTL before TTL, i.e. between the read and the L1
+// fill of TieredCacheSvc.Get.
+type ttlHookCacheSvc struct {
+	CacheSvc
+	onTTL func()
+}
+
+func (s *ttlHookCacheSvc) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if s.onTTL != nil {
+		s.onTTL()
+	}
+	return s.CacheSvc.TTL(ctx, key)
+}
+
+func TestTieredCacheSvc(t *testing.T) {
+	ctx := context.Background()
+	config := &common_utils.BaseConfig{RedisCacheExpire: 60, RedisL1MaxEntries: 2, RedisL1Ttl: time.Minute}
+
+	fake := newFakeRedis()
+	bus := newMemoryBus(2)
+	first := NewTieredCacheSvc(config, NewCacheSvc(config, fake), bus)
+	second := NewTieredCacheSvc(config, NewCacheSvc(config, fake), bus)
+	defer first.CloseClient()
+	defer second.CloseClient()
+	bus.ready.Wait()
+
+	t.Run("Serve reads from L1", func(t *testing.T) {
+		assert.NoError(t, first.Set(ctx, "tiered-key", "v1"))
+
+		var output string
+		assert.NoError(t, second.Get(ctx, "tiered-key", &output))
+		assert.Equal(t, "v1", output)
+
+		// written behind the back of the cache, the L1 copy is served
+		fake.Set(ctx, "tiered-key", []byte(`"v2"`), time.Minute)
+		assert.NoError(t, second.Get(ctx, "tiered-key", &output))
+		assert.Equal(t, "v1", output)
+	})
+
+	t.Run("Evict every instance on Set and Del", func(t *testing.T) {
+		assert.NoError(t, first.Set(ctx, "tiered-key", "v3"))
+
+		var output string
+		assert.NoError(t, second.Get(ctx, "tiered-key", &output))
+		assert.Equal(t, "v3", output)
+
+		assert.NoError(t, first.Del(ctx, "tiered-key"))
+		assert.ErrorIs(t, second.Get(ctx, "tiered-key", &output), redis.Nil)
+	})
+
+	t.Run("Evict every instance on DelByPrefix", func(t *testing.T) {
+		assert.NoError(t, first.Set(ctx, "tiered-prefix-1", 1))
+
+		var output int
+		assert.NoError(t, second.Get(ctx, "tiered-prefix-1", &output))
+
+		first.DelByPrefix(ctx, "tiered-prefix")
+		assert.ErrorIs(t, second.Get(ctx, "tiered-prefix-1", &output), redis.Nil)
+	})
+
+	t.Run("Cap the L1 TTL by the redis TTL", func(t *testing.T) {
+		assert.NoError(t, first.Set(ctx, "tiered-short", "short", 10*time.Millisecond))
+
+		var output string
+		assert.NoError(t, second.Get(ctx, "tiered-short", &output))
+
+		_, ok := second.(*TieredCacheSvc).l1.get("tiered-short", time.Now().Add(20*time.Millisecond))
+		assert.False(t, ok)
+	})
+
+	t.Run("Drop the fill of a key invalidated during the read", func(t *testing.T) {
+		hooked := &ttlHookCacheSvc{CacheSvc: NewCacheSvc(config, fake)}
+		third := NewTieredCacheSvc(config, hooked, nil)
+		defer third.CloseClient()
+
+		assert.NoError(t, third.Set(ctx, "tiered-race", "v1"))
+		hooked.onTTL = func() {
+			hooked.onTTL = nil
+			assert.NoError(t, third.Set(ctx, "tiered-race", "v2"))
+		}
+
+		var output string
+		assert.NoError(t, third.Get(ctx, "tiered-race", &output))
+		assert.Equal(t, "v1", output)
+
+		assert.NoError(t, third.Get(ctx, "tiered-race", &output))
+		assert.Equal(t, "v2", output)
+
+		assert.NoError(t, third.Set(ctx, "tiered-race-prefix", "v1"))
+		hooked.onTTL = func() {
+			hooked.onTTL = nil
+			assert.NoError(t, third.DelByPrefix(ctx, "tiered-race"))
+		}
+		assert.NoError(t, third.Get(ctx, "tiered-race-prefix", &output))
+		assert.ErrorIs(t, third.Get(ctx, "tiered-race-prefix", &output), redis.Nil)
+	})
+
+	t.Run("Limit the L1 size", func(t *testing.T) {
+		for _, key := range []string{"tiered-a", "tiered-b", "tiered-c"} {
+			assert.NoError(t, first.Set(ctx, key, key))
+
+			var output string
+			assert.NoError(t, first.Get(ctx, key, &output))
+		}
+		assert.Equal(t, 2, first.(*TieredCacheSvc).l1.len())
+	})
+}
@@ -65,13 +65,24 @@ func StartAndTracePubsub(ctx context.Context, spanName string, data *pubsub.Mess
 	tracer := otel.GetTracerProvider().Tracer("")
 
 	propagator := otel.GetTextMapPropagator()
-	propagator.Inject(ctx, propagation.MapCarrier(data.Attributes))
+	ctx = propagator.Extract(ctx, propagation.MapCarrier(data.Attributes))
 
 	spanCtx, span := tracer.Start(ctx, spanName)
 
 	return spanCtx, span
 }
 
+// InjectPubsubAttributes adds the trace context of spanCtx to the attributes
+// of a pubsub message, StartAndTracePubsub extracts it on the subscriber side.
+func InjectPubsubAttributes(spanCtx context.Context, attributes map[string]string) map[string]string {
+	if attributes == nil {
+		attributes = make(map[string]string)
+	}
+	otel.GetTextMapPropagator().Inject(spanCtx, propagation.MapCarrier(attributes))
+
+	return attributes
+}
+
 func StartAndTraceKafkaConsumer(ctx context.Context, headers propagation.MapCarrier, spanName string) (context.Context, trace.Span) {
 
 	spanCtx := otel.GetTextMapPropagator().Extract(ctx, headers)
@@ -79,6 +90,15 @@ func StartAndTraceKafkaConsumer(ctx context.Context, headers propagation.MapCarr
 	return StartAndTrace(spanCtx, spanName)
 }
 
+// StartAndTraceKafkaProducer starts a producer span continuing the trace of
+// headers, e.g. the trace context stored with an event that is published
+// later.
+func StartAndTraceKafkaProducer(ctx context.Context, headers propagation.MapCarrier, spanName string) (context.Context, trace.Span) {
+	spanCtx := otel.GetTextMapPropagator().Extract(ctx, headers)
+
+	return otel.GetTracerProvider().Tracer("").Start(spanCtx, spanName, trace.WithSpanKind(trace.SpanKindProducer))
+}
+
 func TextMapCarrierFromKafkaMessageHeaders(headers []kafka.Header) propagation.MapCarrier {
 	textMap := make(map[string]string, len(headers))
 	for _, header := range headers {
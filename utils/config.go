@@ -17,11 +17,46 @@ type BaseConfig struct {
 	ServiceGrpcPort        string        `mapstructure:"SERVICE_GRPC_PORT"`
 	GcpProjectId           string        `mapstructure:"GCP_PROJECT_ID"`
 	PubsubDlq              string        `mapstructure:"PUBSUB_DLQ_TOPIC"`
+	PubsubBatchCount       int           `mapstructure:"PUBSUB_BATCH_COUNT"`
+	PubsubBatchBytes       int           `mapstructure:"PUBSUB_BATCH_BYTES"`
+	PubsubBatchDelay       time.Duration `mapstructure:"PUBSUB_BATCH_DELAY"`
+	PubsubFlowMaxMessages  int           `mapstructure:"PUBSUB_FLOW_MAX_MESSAGES"`
+	PubsubFlowMaxBytes     int           `mapstructure:"PUBSUB_FLOW_MAX_BYTES"`
+	PubsubFlowBlock        bool          `mapstructure:"PUBSUB_FLOW_BLOCK,default=false"`
+	PubsubSubMaxMessages   int           `mapstructure:"PUBSUB_SUB_MAX_MESSAGES"`
+	PubsubSubMaxBytes      int           `mapstructure:"PUBSUB_SUB_MAX_BYTES"`
+	PubsubSubGoroutines    int           `mapstructure:"PUBSUB_SUB_GOROUTINES"`
+	PubsubSubMaxExtension  time.Duration `mapstructure:"PUBSUB_SUB_MAX_EXTENSION"`
+	PubsubSubSynchronous   bool          `mapstructure:"PUBSUB_SUB_SYNCHRONOUS,default=false"`
+	PubsubExactlyOnce      bool          `mapstructure:"PUBSUB_EXACTLY_ONCE,default=false"`
+	PubsubAckDeadline      time.Duration `mapstructure:"PUBSUB_ACK_DEADLINE"`
+	PubsubMinBackoff       time.Duration `mapstructure:"PUBSUB_MIN_BACKOFF"`
+	PubsubMaxBackoff       time.Duration `mapstructure:"PUBSUB_MAX_BACKOFF"`
+	PubsubMaxAttempts      int           `mapstructure:"PUBSUB_MAX_DELIVERY_ATTEMPTS"`
 	RedisHost              string        `mapstructure:"REDIS_HOST"`
 	RedisPort              string        `mapstructure:"REDIS_PORT"`
 	RedisUser              string        `mapstructure:"REDIS_USER"`
 	RedisPassword          string        `mapstructure:"REDIS_PASSWORD"`
+	RedisMode              string        `mapstructure:"REDIS_MODE,default=standalone"`
+	RedisAddrs             []string      `mapstructure:"REDIS_ADDRS"`
+	RedisMasterName        string        `mapstructure:"REDIS_MASTER_NAME"`
+	RedisSentinelPassword  string        `mapstructure:"REDIS_SENTINEL_PASSWORD"`
+	RedisDb                int           `mapstructure:"REDIS_DB,default=0"`
+	RedisTlsEnable         bool          `mapstructure:"REDIS_TLS_ENABLE,default=false"`
+	RedisTlsCaFile         string        `mapstructure:"REDIS_TLS_CA_FILE"`
+	RedisTlsCertFile       string        `mapstructure:"REDIS_TLS_CERT_FILE"`
+	RedisTlsKeyFile        string        `mapstructure:"REDIS_TLS_KEY_FILE"`
+	RedisTlsSkipVerify     bool          `mapstructure:"REDIS_TLS_SKIP_VERIFY,default=false"`
+	RedisPoolSize          int           `mapstructure:"REDIS_POOL_SIZE"`
+	RedisMinIdleConns      int           `mapstructure:"REDIS_MIN_IDLE_CONNS"`
+	RedisDialTimeout       time.Duration `mapstructure:"REDIS_DIAL_TIMEOUT"`
+	RedisReadTimeout       time.Duration `mapstructure:"REDIS_READ_TIMEOUT"`
+	RedisWriteTimeout      time.Duration `mapstructure:"REDIS_WRITE_TIMEOUT"`
+	RedisPoolTimeout       time.Duration `mapstructure:"REDIS_POOL_TIMEOUT"`
 	RedisCacheExpire       int           `mapstructure:"REDIS_DEFAULT_CACHE_EXPIRE"`
+	RedisL1MaxEntries      int           `mapstructure:"REDIS_L1_MAX_ENTRIES"`
+	RedisL1Ttl             time.Duration `mapstructure:"REDIS_L1_TTL"`
+	RedisL1Channel         string        `mapstructure:"REDIS_L1_CHANNEL"`
 	MongoHost              string        `mapstructure:"MONGO_HOST"`
 	MongoPort              string        `mapstructure:"MONGO_PORT"`
 	MongoUser              string        `mapstructure:"MONGO_USER"`
@@ -57,9 +92,35 @@ type BaseConfig struct {
 	KafkaGroupID           string        `mapstructure:"KAFKA_GROUP_ID"`
 	KafkaDlqTopic          string        `mapstructure:"KAFKA_DLQ_TOPIC"`
 	KafkaDlqRetry          int           `mapstructure:"KAFKA_DLQ_RETRY,default=3"`
+	KafkaRetryDelays       []string      `mapstructure:"KAFKA_RETRY_DELAYS"`
+	KafkaDlqMaxReplay      int           `mapstructure:"KAFKA_DLQ_MAX_REPLAY,default=3"`
+	KafkaRegistryUrl       string        `mapstructure:"KAFKA_SCHEMA_REGISTRY_URL"`
+	KafkaRegistryUser      string        `mapstructure:"KAFKA_SCHEMA_REGISTRY_USER"`
+	KafkaRegistryPassword  string        `mapstructure:"KAFKA_SCHEMA_REGISTRY_PASSWORD"`
+	KafkaSaslMechanism     string        `mapstructure:"KAFKA_SASL_MECHANISM"`
+	KafkaSaslUsername      string        `mapstructure:"KAFKA_SASL_USERNAME"`
+	KafkaSaslPassword      string        `mapstructure:"KAFKA_SASL_PASSWORD"`
+	KafkaTlsEnable         bool          `mapstructure:"KAFKA_TLS_ENABLE,default=false"`
+	KafkaTlsCaFile         string        `mapstructure:"KAFKA_TLS_CA_FILE"`
+	KafkaTlsCertFile       string        `mapstructure:"KAFKA_TLS_CERT_FILE"`
+	KafkaTlsKeyFile        string        `mapstructure:"KAFKA_TLS_KEY_FILE"`
+	KafkaTlsSkipVerify     bool          `mapstructure:"KAFKA_TLS_SKIP_VERIFY,default=false"`
+	OutboxTable            string        `mapstructure:"OUTBOX_TABLE,default=kafka_outbox"`
+	OutboxPollInterval     time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL,default=1s"`
+	OutboxBatchSize        int           `mapstructure:"OUTBOX_BATCH_SIZE,default=100"`
+	OutboxMaxAttempts      int           `mapstructure:"OUTBOX_MAX_ATTEMPTS,default=5"`
+	InboxTable             string        `mapstructure:"INBOX_TABLE,default=inbox"`
+	InboxRetention         time.Duration `mapstructure:"INBOX_RETENTION,default=24h"`
+	EventStoreTable        string        `mapstructure:"EVENT_STORE_TABLE,default=events"`
+	EventStoreSnapshotFreq int           `mapstructure:"EVENT_STORE_SNAPSHOT_FREQUENCY,default=100"`
+	ProjectionPollInterval time.Duration `mapstructure:"PROJECTION_POLL_INTERVAL,default=1s"`
+	ProjectionBatchSize    int           `mapstructure:"PROJECTION_BATCH_SIZE,default=100"`
 	KafkaAutoCommit        bool          `mapstructure:"KAFKA_AUTO_COMMIT,default=false"`
 	KafkaAutoTopicCreation bool          `mapstructure:"KAFKA_AUTO_TOPIC_CREATION,default=true"`
 	KafkaReplicationFactor int           `mapstructure:"KAFKA_REPLICATION_FACTOR,default=1"`
+	KafkaDrainTimeout      time.Duration `mapstructure:"KAFKA_DRAIN_TIMEOUT,default=30s"`
+	KafkaConcurrency       int           `mapstructure:"KAFKA_CONCURRENCY,default=10"`
+	KafkaOrdering          string        `mapstructure:"KAFKA_ORDERING,default=partition"`
 }
 
 func LoadBaseConfig(path string, configName string) (*BaseConfig, error) {
@@ -0,0 +1,51 @@
+package common_utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	DefaultPollInterval  = time.Second
+	DefaultPollBatchSize = 100
+)
+
+var (
+	tableNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+	nonWordPattern   = regexp.MustCompile(`\W`)
+)
+
+// ValidTableName reports whether name, optionally schema qualified, can be
+// interpolated into a query.
+func ValidTableName(name string) bool {
+	return tableNamePattern.MatchString(name)
+}
+
+// IndexName returns table with its non word characters replaced, to prefix the
+// names of its indexes.
+func IndexName(table string) string {
+	return nonWordPattern.ReplaceAllString(table, "_")
+}
+
+// RunPoll calls poll until ctx is cancelled. Full batches are polled right
+// away, otherwise it waits for the interval. Errors are logged with name.
+func RunPoll(ctx context.Context, name string, interval time.Duration, batchSize int, poll func(ctx context.Context) (int, error)) error {
+	for {
+		n, err := poll(ctx)
+		if err != nil {
+			LogError(fmt.Sprintf("failed %s: %v", name, err))
+		}
+
+		if err == nil && n == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package common_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidTableName(t *testing.T) {
+	assert.True(t, ValidTableName("events"))
+	assert.True(t, ValidTableName("app.events_v2"))
+	assert.False(t, ValidTableName("events; DROP TABLE users"))
+	assert.False(t, ValidTableName("1events"))
+
+	assert.Equal(t, "app_events", IndexName("app.events"))
+}
+
+func TestRunPoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls []time.Time
+	results := []int{10, 10, 3, 0}
+	err := RunPoll(ctx, "test poll", 20*time.Millisecond, 10, func(ctx context.Context) (int, error) {
+		calls = append(calls, time.Now())
+		if len(calls) == len(results) {
+			cancel()
+			return 0, errors.New("stopped")
+		}
+		return results[len(calls)-1], nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, calls, 4)
+
+	// full batches are polled right away, the partial one waits
+	assert.Less(t, calls[2].Sub(calls[0]), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, calls[3].Sub(calls[2]), 20*time.Millisecond)
+}
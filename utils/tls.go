@@ -0,0 +1,57 @@
+package common_utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// TLSOptions are the TLS settings of a client, e.g. the KAFKA_TLS_* or
+// REDIS_TLS_* config.
+type TLSOptions struct {
+	Enable bool
+	// SkipVerify is only meant for development servers with self signed
+	// certificates.
+	SkipVerify bool
+	CaFile     string
+	CertFile   string
+	KeyFile    string
+}
+
+// NewTLSConfig returns the TLS config of opts, nil when TLS is disabled. The
+// CA file replaces the system roots and the cert and key files set the client
+// certificate.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if !opts.Enable {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.SkipVerify,
+	}
+
+	if opts.CaFile != "" {
+		ca, err := os.ReadFile(opts.CaFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("tls ca file contains no certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,31 @@
+package common_utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConfig, err := NewTLSConfig(TLSOptions{CaFile: "does-not-exist.pem"})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = NewTLSConfig(TLSOptions{Enable: true, SkipVerify: true})
+	assert.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	_, err = NewTLSConfig(TLSOptions{Enable: true, CaFile: "does-not-exist.pem"})
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+	_, err = NewTLSConfig(TLSOptions{Enable: true, CaFile: caFile})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(TLSOptions{Enable: true, CertFile: "does-not-exist.pem"})
+	assert.Error(t, err)
+}